@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"strings"
+
+	"github.com/mach-composer/mach-composer-plugin-helpers/helpers"
+	"github.com/mach-composer/mach-composer-plugin-sdk/schema"
+)
+
+// The data available in the component templates
+type componentData struct {
+	*VercelConfig
+
+	// Prefix of the variable names, set when a component has multiple projects
+	Prefix string
+	// Name of the terraform resources in managed mode
+	ResourceName string
+	// The objects of all projects for the object output format
+	ProjectObjects map[string]ProjectConfigObject
+	// Reference of the aliased provider, empty for the default provider
+	Provider string
+}
+
+// Returns the vercel_project_config variable of the object output format
+func (d componentData) RenderProjectObject() string {
+	return renderHCL(d.Prefix+"vercel_project_config", d.ProjectObject())
+}
+
+// Returns the vercel_projects variable of the object output format
+func (d componentData) RenderProjectObjects() string {
+	return renderHCL("vercel_projects", d.ProjectObjects)
+}
+
+// Renders the variables, and in managed mode the resources, for all projects
+// of a component. The alias is the provider alias the component uses, if any.
+func renderComponent(component string, cfg *VercelConfig, alias string) (*schema.ComponentSchema, error) {
+	projects := cfg.projects()
+	for _, project := range projects {
+		if err := project.Config.ProjectConfig.RollingRelease.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Mode == modeManaged {
+		return renderManagedComponent(component, projects, alias)
+	}
+
+	if cfg.OutputFormat == outputFormatObject && len(cfg.Projects) > 0 {
+		data := componentData{
+			VercelConfig:   cfg,
+			ProjectObjects: make(map[string]ProjectConfigObject, len(projects)),
+		}
+		for _, project := range projects {
+			data.ProjectObjects[project.Name] = project.Config.ProjectObject()
+		}
+
+		vars, err := helpers.RenderGoTemplate(variablesTemplate(objectProjectsVariables(cfg.FormatVersion)), data)
+		if err != nil {
+			return nil, err
+		}
+		return &schema.ComponentSchema{Variables: vars}, nil
+	}
+
+	variables := componentVariables
+	if cfg.OutputFormat == outputFormatObject {
+		variables = objectVariables(cfg.FormatVersion)
+	}
+
+	var sb strings.Builder
+	for _, project := range projects {
+		vars, err := helpers.RenderGoTemplate(variablesTemplate(variables), componentData{
+			VercelConfig: project.Config,
+			Prefix:       projectPrefix(project.Name),
+		})
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(vars)
+	}
+
+	return &schema.ComponentSchema{Variables: sb.String()}, nil
+}
+
+// Returns the prefix of the variable names of an additional project
+func projectPrefix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return helpers.Slugify(name) + "_"
+}
@@ -1,17 +1,132 @@
 package internal
 
 import (
+	"fmt"
+	"reflect"
 	"sort"
+	"strings"
 
-	"github.com/google/go-cmp/cmp"
 	"github.com/mach-composer/mach-composer-plugin-helpers/helpers"
+	"github.com/mitchellh/mapstructure"
 	"golang.org/x/exp/slices"
 )
 
 type VercelConfig struct {
-	TeamID        string        `mapstructure:"team_id"`
-	APIToken      string        `mapstructure:"api_token"`
-	ProjectConfig ProjectConfig `mapstructure:"project_config"`
+	TeamID        string        `mapstructure:"team_id" merge:"override"`
+	APIToken      string        `mapstructure:"api_token" merge:"override" schema:"secret"`
+	Mode          string        `mapstructure:"mode" merge:"override" schema:"enum=variables|managed"`
+	OutputFormat  string        `mapstructure:"output_format" merge:"override" schema:"enum=flat|object"`
+	ProjectConfig ProjectConfig `mapstructure:"project_config" merge:"deep"`
+
+	// Version of the vercel_project_config object, so modules adopt new
+	// attributes deliberately
+	FormatVersion int `mapstructure:"format_version" merge:"override" schema:"minimum=1,maximum=2" description:"Version of the vercel_project_config object of the object output format, defaults to 1"`
+
+	// Alias of the vercel provider used by a component with a different team
+	// or api token than its site
+	ProviderAlias string `mapstructure:"provider_alias" merge:"override" schema:"levels=component" description:"Alias of the vercel provider when the team_id or api_token differs from the site"`
+
+	// Rejects literal values in the sensitive fields
+	RequireSecretReferences *bool `mapstructure:"require_secret_references" merge:"override" schema:"levels=global|site" description:"Reject literal values in api_token, password_protection.password and environment variable values"`
+
+	// Named sets of environment variables which project configs can use
+	EnvironmentVariableGroups map[string][]ProjectEnvironmentVariable `mapstructure:"environment_variable_groups" merge:"deep" schema:"levels=global" description:"Named sets of environment variables which project configs can use with use_groups"`
+
+	// Named partial project configs which project configs can extend
+	Presets map[string]ProjectConfig `mapstructure:"presets" merge:"deep" schema:"levels=global" description:"Named partial project configs which project configs can extend"`
+
+	// Additional projects of a component by logical name, each inheriting
+	// from the project_config
+	Projects map[string]ProjectConfig `mapstructure:"projects" merge:"deep" schema:"levels=component" description:"Additional projects by logical name, inheriting from project_config"`
+
+	// Overrides per mach-composer environment, applied on top of the level
+	// they are defined on
+	Environments map[string]VercelConfig `mapstructure:"environments" merge:"ignore" description:"Overrides per mach-composer environment"`
+
+	// Fields which are cleared on this level
+	Unset unsetFields `mapstructure:"unset" merge:"ignore"`
+
+	// Kinds of the secret references on this level, including its environment
+	// overrides, by the expression they are decoded into
+	SecretReferences map[string]string `mapstructure:"-" merge:"ignore"`
+}
+
+// Decodes the raw plugin configuration of a level into a VercelConfig. Lists
+// with the single option may also be given as a single string and secrets as
+// a secret reference. A null or !unset value clears the value inherited from the parent
+// level. Unknown fields are reported together, with a suggestion for each, as
+// are fields which only apply to other levels. The description names the level
+// in errors. Dotenv files are read relative to the directory of the
+// configuration.
+func decodeConfig(data map[string]any, level string, description string, dir string) (*VercelConfig, error) {
+	cfg := NewVercelConfig()
+	references := map[string]string{}
+
+	var metadata mapstructure.Metadata
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(stringToSliceHook, secretReferenceHook(references), valueFromHook, unsetHook),
+		Metadata:   &metadata,
+		Result:     &cfg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(data); err != nil {
+		return nil, err
+	}
+	if err := unknownFieldsError(description, metadata.Unused); err != nil {
+		return nil, err
+	}
+	if err := misplacedFieldsError(level, description, data); err != nil {
+		return nil, err
+	}
+	if len(references) > 0 {
+		cfg.SecretReferences = references
+	}
+	if err := cfg.validateRollingReleases(); err != nil {
+		return nil, fmt.Errorf("%s: %w", description, err)
+	}
+	if err := cfg.loadEnvironmentVariablesFiles(dir); err != nil {
+		return nil, fmt.Errorf("%s: %w", description, err)
+	}
+
+	return &cfg, nil
+}
+
+// Converts a single string given for a list of strings with the single option
+// in its schema tag into a list. Other lists only accept lists.
+func stringToSliceHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.Map || to.Kind() != reflect.Struct {
+		return data, nil
+	}
+	values, ok := data.(map[string]any)
+	if !ok {
+		return data, nil
+	}
+
+	var result map[string]any
+	for i := 0; i < to.NumField(); i++ {
+		field := to.Field(i)
+		if _, single := schemaOptions(field)["single"]; !single {
+			continue
+		}
+		name := field.Tag.Get("mapstructure")
+		value, ok := values[name].(string)
+		if !ok || value == unsetValue {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]any, len(values))
+			for key, v := range values {
+				result[key] = v
+			}
+		}
+		result[name] = []string{value}
+	}
+	if result == nil {
+		return data, nil
+	}
+	return result, nil
 }
 
 // Creates a new VercelConfig with default values
@@ -28,193 +143,379 @@ func NewVercelConfig() VercelConfig {
 	}
 }
 
+// Returns the config of the parent level with this level merged into it
 func (c *VercelConfig) extendConfig(o *VercelConfig) *VercelConfig {
-	if o != nil && o != (&VercelConfig{}) {
-		cfg := &VercelConfig{
-			TeamID:        o.TeamID,
-			APIToken:      o.APIToken,
-			ProjectConfig: o.ProjectConfig,
-		}
+	return mergeStructs(o, c)
+}
 
-		if c.TeamID != "" {
-			cfg.TeamID = c.TeamID
-		}
-		if c.APIToken != "" {
-			cfg.APIToken = c.APIToken
-		}
-		if !cmp.Equal(c.ProjectConfig, ProjectConfig{}) {
-			// Update individual fields instead of updating struct
-			result := c.ProjectConfig.extendConfig(&o.ProjectConfig)
-			if result != nil {
-				cfg.ProjectConfig = *result
-			}
-		}
-		return cfg
+// Returns a copy of the config which can be modified without changing the
+// original, for example when applying defaults
+func (c *VercelConfig) clone() *VercelConfig {
+	cfg := *c
+	cfg.ProjectConfig.EnvironmentVariables = slices.Clone(c.ProjectConfig.EnvironmentVariables)
+	return &cfg
+}
+
+// Sets the defaults for all fields which are not configured on any level
+func (c *VercelConfig) applyDefaults() {
+	if c.Mode == "" {
+		c.Mode = modeVariables
 	}
 
-	return c
-}
+	if c.OutputFormat == "" {
+		c.OutputFormat = outputFormatFlat
+	}
 
-type ProjectConfig struct {
-	Name                          string                       `mapstructure:"name"`
-	Framework                     string                       `mapstructure:"framework"`
-	ManualProductionDeployment    *bool                        `mapstructure:"manual_production_deployment"`
-	ServerlessFunctionRegion      string                       `mapstructure:"serverless_function_region"`
-	EnvironmentVariables          []ProjectEnvironmentVariable `mapstructure:"environment_variables"`
-	GitRepository                 GitRepository                `mapstructure:"git_repository"`
-	BuildCommand                  string                       `mapstructure:"build_command"`
-	IgnoreCommand                 string                       `mapstructure:"ignore_command"`
-	RootDirectory                 string                       `mapstructure:"root_directory"`
-	NodeVersion                   string                       `mapstructure:"node_version"`
-	ProjectDomains                []ProjectDomain              `mapstructure:"domains"`
-	ProtectionBypassForAutomation bool                         `mapstructure:"protection_bypass_for_automation"`
-	PasswordProtection            PasswordProtection           `mapstructure:"password_protection"`
-	VercelAuthentication          VercelAuthentication         `mapstructure:"vercel_authentication"`
+	if c.FormatVersion == 0 {
+		c.FormatVersion = 1
+	}
+
+	c.ProjectConfig.applyDefaults()
 }
 
-func (c *ProjectConfig) extendConfig(o *ProjectConfig) *ProjectConfig {
-	if o != nil && o != (&ProjectConfig{}) {
-		cfg := &ProjectConfig{
-			Name:                          o.Name,
-			Framework:                     o.Framework,
-			ServerlessFunctionRegion:      o.ServerlessFunctionRegion,
-			BuildCommand:                  o.BuildCommand,
-			IgnoreCommand:                 o.IgnoreCommand,
-			RootDirectory:                 o.RootDirectory,
-			NodeVersion:                   o.NodeVersion,
-			ManualProductionDeployment:    o.ManualProductionDeployment,
-			EnvironmentVariables:          o.EnvironmentVariables,
-			GitRepository:                 o.GitRepository,
-			ProtectionBypassForAutomation: o.ProtectionBypassForAutomation,
-			PasswordProtection:            o.PasswordProtection,
-			VercelAuthentication:          o.VercelAuthentication,
-			ProjectDomains:                o.ProjectDomains,
-		}
+// A project of a component with the project_config of the component applied
+type namedProject struct {
+	// Logical name of the project, empty for the project_config itself
+	Name   string
+	Config *VercelConfig
+}
 
-		if c.Name != "" {
-			cfg.Name = c.Name
+// Returns the projects to render for a component. Without additional projects
+// this is the project_config itself.
+func (c *VercelConfig) projects() []namedProject {
+	result := c.mergedProjects()
+	if len(c.Projects) > 0 {
+		for _, project := range result {
+			project.Config.ProjectConfig.applyDefaults()
 		}
+	}
+	return result
+}
 
-		if c.Framework != "" {
-			cfg.Framework = c.Framework
-		}
+// Returns the projects of a component like projects, without applying the
+// defaults to the additional projects.
+func (c *VercelConfig) mergedProjects() []namedProject {
+	if len(c.Projects) == 0 {
+		return []namedProject{{Config: c}}
+	}
 
-		if c.ServerlessFunctionRegion != "" {
-			cfg.ServerlessFunctionRegion = c.ServerlessFunctionRegion
-		}
+	names := make([]string, 0, len(c.Projects))
+	for name := range c.Projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]namedProject, 0, len(names))
+	for _, name := range names {
+		project := c.Projects[name]
+		cfg := *c
+		cfg.ProjectConfig = *project.extendConfig(&c.ProjectConfig)
+		result = append(result, namedProject{Name: name, Config: &cfg})
+	}
+	return result
+}
 
-		if c.BuildCommand != "" {
-			cfg.BuildCommand = c.BuildCommand
-		}
+// Returns the config with the overrides of the given environment applied
+func (c *VercelConfig) forEnvironment(environment string) *VercelConfig {
+	override, ok := c.Environments[environment]
+	if !ok {
+		return c
+	}
 
-		if c.IgnoreCommand != "" {
-			cfg.IgnoreCommand = c.IgnoreCommand
-		}
+	cfg := override.extendConfig(c)
 
-		if c.RootDirectory != "" {
-			cfg.RootDirectory = c.RootDirectory
-		}
+	// The override takes precedence over the variables of its own level
+	if override.ProjectConfig.EnvironmentVariablesMerge == "" {
+		cfg.ProjectConfig.EnvironmentVariables = MergeEnvironmentVariables(c.ProjectConfig.EnvironmentVariables, override.ProjectConfig.EnvironmentVariables)
+	}
 
-		if c.NodeVersion != "" {
-			cfg.NodeVersion = c.NodeVersion
-		}
+	return cfg
+}
 
-		if c.ManualProductionDeployment != nil {
-			cfg.ManualProductionDeployment = c.ManualProductionDeployment
-		}
+type ProjectConfig struct {
+	Name                          string                       `mapstructure:"name" merge:"override" template:"true"`
+	Framework                     string                       `mapstructure:"framework" merge:"override"`
+	ManualProductionDeployment    *bool                        `mapstructure:"manual_production_deployment" merge:"override"`
+	ServerlessFunctionRegion      string                       `mapstructure:"serverless_function_region" merge:"override"`
+	ServerlessFunctionRegions     []string                     `mapstructure:"serverless_function_regions" merge:"keyed" schema:"single"`
+	EnvironmentVariables          []ProjectEnvironmentVariable `mapstructure:"environment_variables" merge:"keyed=key"`
+	Extends                       []string                     `mapstructure:"extends" merge:"ignore" description:"Presets of the global config to apply before the site and component, in order"`
+	UseGroups                     []string                     `mapstructure:"use_groups" merge:"ignore" description:"Environment variable groups of the global config to add to the environment variables, in order"`
+	EnvironmentVariablesFiles     []EnvironmentVariablesFile   `mapstructure:"environment_variables_files" merge:"ignore" description:"Dotenv files with environment variables, relative to the mach-composer configuration"`
+	EnvironmentVariablesMode      string                       `mapstructure:"environment_variables_mode" merge:"override" schema:"enum=inline|bulk"`
+	CustomEnvironments            []string                     `mapstructure:"custom_environments" merge:"keyed" description:"Custom environments of the project which environment variables may target"`
+	GitRepository                 GitRepository                `mapstructure:"git_repository" merge:"deep"`
+	BuildCommand                  string                       `mapstructure:"build_command" merge:"override" template:"true"`
+	IgnoreCommand                 string                       `mapstructure:"ignore_command" merge:"override"`
+	Ignore                        IgnoreConfig                 `mapstructure:"ignore" merge:"deep" description:"Settings from which the ignore_command is generated"`
+	RootDirectory                 string                       `mapstructure:"root_directory" merge:"override" template:"true"`
+	NodeVersion                   string                       `mapstructure:"node_version" merge:"override"`
+	ProjectDomains                []ProjectDomain              `mapstructure:"domains" merge:"keyed=domain"`
+	ProtectionBypassForAutomation *bool                        `mapstructure:"protection_bypass_for_automation" merge:"override"`
+	PasswordProtection            PasswordProtection           `mapstructure:"password_protection" merge:"deep"`
+	VercelAuthentication          VercelAuthentication         `mapstructure:"vercel_authentication" merge:"deep"`
+	RollingRelease                RollingRelease               `mapstructure:"rolling_release" merge:"deep"`
+	Routing                       Routing                      `mapstructure:"routing" merge:"deep" description:"Headers, redirects and rewrites of the vercel.json of the project"`
+	Crons                         []Cron                       `mapstructure:"crons" merge:"keyed=path" description:"Cron jobs of the vercel.json of the project"`
+	Functions                     map[string]FunctionConfig    `mapstructure:"functions" merge:"deep" description:"Settings of the functions of the vercel.json of the project by source glob"`
+
+	// Directives which define how the lists of this level are merged into the
+	// lists of the parent level: replace, append or merge_by_key
+	EnvironmentVariablesMerge      string `mapstructure:"environment_variables_merge" merge:"directive"`
+	ProjectDomainsMerge            string `mapstructure:"domains_merge" merge:"directive"`
+	ServerlessFunctionRegionsMerge string `mapstructure:"serverless_function_regions_merge" merge:"directive"`
+	CustomEnvironmentsMerge        string `mapstructure:"custom_environments_merge" merge:"directive"`
+	CronsMerge                     string `mapstructure:"crons_merge" merge:"directive"`
+
+	// Fields which are cleared on this level
+	Unset unsetFields `mapstructure:"unset" merge:"ignore"`
+}
 
-		if c.GitRepository.Type != "" || c.GitRepository.Repo != "" || c.GitRepository.ProductionBranch != "" {
-			result := c.GitRepository.extendConfig(&o.GitRepository)
-			if result != nil {
-				cfg.GitRepository = *result
-			} else {
-				cfg.GitRepository = c.GitRepository
-			}
+// Returns the project config of the parent level with this level merged into
+// it
+func (c *ProjectConfig) extendConfig(o *ProjectConfig) *ProjectConfig {
+	return mergeStructs(o, c)
+}
 
-		}
+func (c *ProjectConfig) applyDefaults() {
+	if c.VercelAuthentication.DeploymentType == "" {
+		c.VercelAuthentication.DeploymentType = "standard_protection"
+	}
 
-		if c.ProtectionBypassForAutomation {
-			cfg.ProtectionBypassForAutomation = c.ProtectionBypassForAutomation
-		}
+	if c.PasswordProtection.DeploymentType == "" {
+		c.PasswordProtection.DeploymentType = "standard_protection"
+	}
 
-		if c.VercelAuthentication.DeploymentType != "" {
-			cfg.VercelAuthentication.DeploymentType = c.VercelAuthentication.DeploymentType
+	// Default behavior for Vercel is to output to all environments
+	// Set this as default field unless manually filled
+	for i := range c.EnvironmentVariables {
+		if len(c.EnvironmentVariables[i].Environment) == 0 {
+			c.EnvironmentVariables[i].Environment = []string{"development", "preview", "production"}
 		}
+	}
 
-		if c.PasswordProtection.Password != "" {
-			cfg.PasswordProtection = c.PasswordProtection
-		}
+	if c.EnvironmentVariablesMode == "" {
+		c.EnvironmentVariablesMode = "inline"
+	}
 
-		cfg.EnvironmentVariables = MergeEnvironmentVariables(c.EnvironmentVariables, o.EnvironmentVariables)
+	if len(c.RollingRelease.Stages) > 0 && c.RollingRelease.AdvancementType == "" {
+		c.RollingRelease.AdvancementType = "automatic"
+	}
 
-		if !slices.EqualFunc(c.ProjectDomains, o.ProjectDomains, func(c, o ProjectDomain) bool {
-			return c.Domain == o.Domain && c.GitBranch == o.GitBranch && c.Redirect == o.Redirect && c.RedirectStatusCode == o.RedirectStatusCode
-		}) {
-			// Append missing project domains
-			cfg.ProjectDomains = append(cfg.ProjectDomains, c.ProjectDomains...)
-		}
+	// keep existing behavior to false when omitted
+	if c.ManualProductionDeployment == nil {
+		defaultFalse := false
+		c.ManualProductionDeployment = &defaultFalse
+	}
 
-		return cfg
+	if c.ProtectionBypassForAutomation == nil {
+		defaultFalse := false
+		c.ProtectionBypassForAutomation = &defaultFalse
 	}
+}
 
-	return c
+// The settings from which the ignored build step command is generated. The
+// paths are relative to the root directory of the project.
+type IgnoreConfig struct {
+	Paths             []string    `mapstructure:"paths" merge:"keyed" template:"true" description:"Paths whose changes trigger a build"`
+	TurboWorkspace    string      `mapstructure:"turbo_workspace" merge:"override" template:"true" description:"Workspace whose changes, including its dependencies, trigger a build according to turbo-ignore"`
+	SkipBranches      []string    `mapstructure:"skip_branches" merge:"keyed" description:"Branches which are never built"`
+	PathsMerge        string      `mapstructure:"paths_merge" merge:"directive"`
+	SkipBranchesMerge string      `mapstructure:"skip_branches_merge" merge:"directive"`
+	Unset             unsetFields `mapstructure:"unset" merge:"ignore"`
 }
 
 type GitRepository struct {
-	ProductionBranch string `mapstructure:"production_branch"`
-	Type             string `mapstructure:"type"`
-	Repo             string `mapstructure:"repo"`
+	ProductionBranch string      `mapstructure:"production_branch" merge:"override"`
+	Type             string      `mapstructure:"type" merge:"override"`
+	Repo             string      `mapstructure:"repo" merge:"override"`
+	Unset            unsetFields `mapstructure:"unset" merge:"ignore"`
 }
 
-func (c *GitRepository) extendConfig(o *GitRepository) *GitRepository {
-	if o != nil && o != (&GitRepository{}) {
-		cfg := &GitRepository{
-			ProductionBranch: o.ProductionBranch,
-			Type:             o.Type,
-			Repo:             o.Repo,
-		}
+type PasswordProtection struct {
+	Password       string      `mapstructure:"password" merge:"override" schema:"secret"`
+	DeploymentType string      `mapstructure:"deployment_type" merge:"override" schema:"enum=standard_protection|all_deployments|only_production_deployments|only_preview_deployments"`
+	Unset          unsetFields `mapstructure:"unset" merge:"ignore"`
+}
 
-		if c.ProductionBranch != "" {
-			cfg.ProductionBranch = c.ProductionBranch
-		}
+type VercelAuthentication struct {
+	DeploymentType string      `mapstructure:"deployment_type" merge:"override" schema:"enum=standard_protection|all_deployments|only_production_deployments|only_preview_deployments"`
+	Unset          unsetFields `mapstructure:"unset" merge:"ignore"`
+}
 
-		if c.Type != "" {
-			cfg.Type = c.Type
+type RollingRelease struct {
+	AdvancementType string                `mapstructure:"advancement_type" merge:"override" schema:"enum=automatic|manual-approval"`
+	Stages          []RollingReleaseStage `mapstructure:"stages" merge:"override" description:"Stages with strictly increasing target percentages, of which the last is 100"`
+	Unset           unsetFields           `mapstructure:"unset" merge:"ignore"`
+}
+
+type RollingReleaseStage struct {
+	TargetPercentage int64 `mapstructure:"target_percentage" schema:"required,minimum=1,maximum=100"`
+	Duration         int64 `mapstructure:"duration"`
+}
+
+// Validates the rolling release stages of the project configs of a level,
+// including those of the presets, projects and environment overrides. The
+// stages of a level replace those of its parent, so each level is checked on
+// its own.
+func (c *VercelConfig) validateRollingReleases() error {
+	if err := c.ProjectConfig.RollingRelease.validate(); err != nil {
+		return fmt.Errorf("project_config.%w", err)
+	}
+	for _, name := range sortedKeys(c.Presets) {
+		preset := c.Presets[name]
+		if err := preset.RollingRelease.validate(); err != nil {
+			return fmt.Errorf("presets.%s.%w", name, err)
+		}
+	}
+	for _, name := range sortedKeys(c.Projects) {
+		project := c.Projects[name]
+		if err := project.RollingRelease.validate(); err != nil {
+			return fmt.Errorf("projects.%s.%w", name, err)
 		}
+	}
+	for _, name := range sortedKeys(c.Environments) {
+		override := c.Environments[name]
+		if err := override.validateRollingReleases(); err != nil {
+			return fmt.Errorf("environments.%s.%w", name, err)
+		}
+	}
+	return nil
+}
 
-		if c.Repo != "" {
-			cfg.Repo = c.Repo
+// Checks whether the stages gradually shift traffic to the new deployment,
+// meaning the target percentages strictly increase and end at 100.
+func (c *RollingRelease) validate() error {
+	if len(c.Stages) == 0 {
+		return nil
+	}
+
+	previous := int64(0)
+	for i, stage := range c.Stages {
+		if stage.TargetPercentage <= previous {
+			return fmt.Errorf("rolling_release.stages[%d]: target_percentage %d must be greater than %d", i, stage.TargetPercentage, previous)
 		}
+		previous = stage.TargetPercentage
+	}
 
-		return cfg
+	if previous != 100 {
+		return fmt.Errorf("rolling_release.stages: last target_percentage must be 100, got %d", previous)
 	}
 
-	return c
+	return nil
 }
 
-type PasswordProtection struct {
-	Password       string `mapstructure:"password"`
-	DeploymentType string `mapstructure:"deployment_type"`
+// The routes of the vercel.json of a project. The lists are merged by source
+// unless their directive says otherwise.
+type Routing struct {
+	Headers   []RouteHeaders `mapstructure:"headers" merge:"keyed=source"`
+	Redirects []Redirect     `mapstructure:"redirects" merge:"keyed=source"`
+	Rewrites  []Rewrite      `mapstructure:"rewrites" merge:"keyed=source"`
+
+	HeadersMerge   string `mapstructure:"headers_merge" merge:"directive"`
+	RedirectsMerge string `mapstructure:"redirects_merge" merge:"directive"`
+	RewritesMerge  string `mapstructure:"rewrites_merge" merge:"directive"`
+
+	Unset unsetFields `mapstructure:"unset" merge:"ignore"`
 }
 
-type VercelAuthentication struct {
-	DeploymentType string `mapstructure:"deployment_type"`
+type RouteHeaders struct {
+	Source  string        `mapstructure:"source" schema:"required"`
+	Headers []RouteHeader `mapstructure:"headers"`
+}
+
+type RouteHeader struct {
+	Key   string `mapstructure:"key" schema:"required"`
+	Value string `mapstructure:"value" schema:"required"`
+}
+
+type Redirect struct {
+	Source      string `mapstructure:"source" schema:"required"`
+	Destination string `mapstructure:"destination" schema:"required"`
+	Permanent   *bool  `mapstructure:"permanent"`
+	StatusCode  int64  `mapstructure:"status_code" schema:"enum=301|302|307|308"`
+}
+
+type Rewrite struct {
+	Source      string `mapstructure:"source" schema:"required"`
+	Destination string `mapstructure:"destination" schema:"required"`
+}
+
+type Cron struct {
+	Path     string `mapstructure:"path" schema:"required"`
+	Schedule string `mapstructure:"schedule" schema:"required"`
+}
+
+type FunctionConfig struct {
+	Memory      int64       `mapstructure:"memory" merge:"override"`
+	MaxDuration int64       `mapstructure:"max_duration" merge:"override"`
+	Unset       unsetFields `mapstructure:"unset" merge:"ignore"`
 }
 
 type ProjectEnvironmentVariable struct {
-	Key         string   `mapstructure:"key"`
-	Value       string   `mapstructure:"value"`
+	Key         string     `mapstructure:"key"`
+	Value       string     `mapstructure:"value" schema:"secret" template:"true"`
+	ValueFrom   *ValueFrom `mapstructure:"value_from" description:"Terraform expression or output of another component rendered as the value"`
+	Environment []string   `mapstructure:"environment"`
+	// Path of the dotenv file the variable was read from as configured,
+	// empty for the variables of the configuration. Values of dotenv files
+	// are taken literally, so they are not evaluated as templates and are
+	// escaped when rendered.
+	Source string `mapstructure:"-"`
+}
+
+// A dotenv file with environment variables for the given environments, all
+// environments when none are given
+type EnvironmentVariablesFile struct {
+	Path        string   `mapstructure:"path" schema:"required" description:"Path of the dotenv file. Relative paths are resolved against VERCEL_PLUGIN_CONFIG_DIR, which must be set when running mach-composer as it does not pass its directory to the plugin"`
 	Environment []string `mapstructure:"environment"`
 }
 
+// The source of an environment variable value which is rendered as a raw
+// terraform expression: either an expression, or an output of another
+// component.
+type ValueFrom struct {
+	Expression string `mapstructure:"expression"`
+	Component  string `mapstructure:"component"`
+	Output     string `mapstructure:"output"`
+}
+
+// Returns the HCL of the value, rendering a terraform expression such as the
+// expression of value_from as is
+func (c *ProjectEnvironmentVariable) DisplayValue() string {
+	return renderHCL("value", c.renderedValue())
+}
+
+// Returns the value as passed to renderHCL, where the `${` of the literal
+// values of dotenv files is escaped as `$${`
+func (c *ProjectEnvironmentVariable) renderedValue() string {
+	if c.Source == "" {
+		return c.Value
+	}
+	return strings.ReplaceAll(c.Value, "${", "$${")
+}
+
 // Returns a HCL-friendly version of the list of environments which are
 // encapsulated by quotes and are comma separated
 func (c *ProjectEnvironmentVariable) DisplayEnvironments() string {
 	return helpers.SerializeToHCL("environment", c.Environment)
 }
 
+// Returns a HCL-friendly version of the list of environments using the
+// `target` attribute name of the vercel_project_environment_variables resource
+func (c *ProjectEnvironmentVariable) DisplayTargets() string {
+	return helpers.SerializeToHCL("target", c.Environment)
+}
+
+// The value of an environment variable together with the dotenv file it was
+// read from
+type sourcedValue struct {
+	value  string
+	source string
+}
+
 func MergeEnvironmentVariables(o []ProjectEnvironmentVariable, c []ProjectEnvironmentVariable) []ProjectEnvironmentVariable {
-	merged := make(map[string]map[string]string, len(o)+len(c))
+	merged := make(map[string]map[string]sourcedValue, len(o)+len(c))
 
 	// process parent environments
 	for _, env := range o {
@@ -224,9 +525,9 @@ func MergeEnvironmentVariables(o []ProjectEnvironmentVariable, c []ProjectEnviro
 		}
 		for _, environment := range env.Environment {
 			if _, exists := merged[env.Key]; !exists {
-				merged[env.Key] = make(map[string]string, 3)
+				merged[env.Key] = make(map[string]sourcedValue, 3)
 			}
-			merged[env.Key][environment] = env.Value
+			merged[env.Key][environment] = sourcedValue{env.Value, env.Source}
 		}
 	}
 
@@ -238,9 +539,9 @@ func MergeEnvironmentVariables(o []ProjectEnvironmentVariable, c []ProjectEnviro
 		}
 		for _, environment := range env.Environment {
 			if _, exists := merged[env.Key]; !exists {
-				merged[env.Key] = make(map[string]string, 3)
+				merged[env.Key] = make(map[string]sourcedValue, 3)
 			}
-			merged[env.Key][environment] = env.Value
+			merged[env.Key][environment] = sourcedValue{env.Value, env.Source}
 		}
 	}
 
@@ -248,7 +549,7 @@ func MergeEnvironmentVariables(o []ProjectEnvironmentVariable, c []ProjectEnviro
 	result := []ProjectEnvironmentVariable{}
 	for key, envMap := range merged {
 		// Group variables by value to consolidate environments
-		valueGroups := make(map[string][]string)
+		valueGroups := make(map[sourcedValue][]string)
 
 		for environment, value := range envMap {
 			valueGroups[value] = append(valueGroups[value], environment)
@@ -262,8 +563,9 @@ func MergeEnvironmentVariables(o []ProjectEnvironmentVariable, c []ProjectEnviro
 
 			result = append(result, ProjectEnvironmentVariable{
 				Key:         key,
-				Value:       value,
+				Value:       value.value,
 				Environment: environments,
+				Source:      value.source,
 			})
 		}
 	}
@@ -276,9 +578,25 @@ func MergeEnvironmentVariables(o []ProjectEnvironmentVariable, c []ProjectEnviro
 	return result
 }
 
+// Returns the parent values followed by the child values which are not part
+// of the parent yet
+func mergeStrings(o []string, c []string) []string {
+	if len(c) == 0 {
+		return o
+	}
+
+	result := slices.Clone(o)
+	for _, value := range c {
+		if !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
 type ProjectDomain struct {
-	Domain             string `mapstructure:"domain"`
-	GitBranch          string `mapstructure:"git_branch"`
-	Redirect           string `mapstructure:"redirect"`
-	RedirectStatusCode int64  `mapstructure:"redirect_status_code"`
+	Domain             string `mapstructure:"domain" template:"true"`
+	GitBranch          string `mapstructure:"git_branch" template:"true"`
+	Redirect           string `mapstructure:"redirect" template:"true"`
+	RedirectStatusCode int64  `mapstructure:"redirect_status_code" schema:"enum=301|302|307|308"`
 }
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -170,3 +172,234 @@ func TestMergeList(t *testing.T) {
 		assert.ElementsMatch(t, []string{"development", "preview", "production"}, result[0].Environment)
 	})
 }
+
+func TestRollingReleaseValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		stages  []RollingReleaseStage
+		wantErr string
+	}{
+		{name: "no stages", stages: nil},
+		{name: "single stage", stages: []RollingReleaseStage{{TargetPercentage: 100}}},
+		{name: "increasing stages", stages: []RollingReleaseStage{{TargetPercentage: 5, Duration: 10}, {TargetPercentage: 50, Duration: 30}, {TargetPercentage: 100}}},
+		{name: "not increasing", stages: []RollingReleaseStage{{TargetPercentage: 50}, {TargetPercentage: 50}, {TargetPercentage: 100}}, wantErr: "rolling_release.stages[1]: target_percentage 50 must be greater than 50"},
+		{name: "not ending at 100", stages: []RollingReleaseStage{{TargetPercentage: 10}, {TargetPercentage: 80}}, wantErr: "last target_percentage must be 100, got 80"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := RollingRelease{Stages: tc.stages}
+			err := rr.validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeStrings(t *testing.T) {
+	assert.Equal(t, []string{"fra1"}, mergeStrings([]string{"fra1"}, nil))
+	assert.Equal(t, []string{"iad1"}, mergeStrings(nil, []string{"iad1"}))
+	assert.Equal(t, []string{"fra1", "iad1", "sfo1"}, mergeStrings([]string{"fra1", "iad1"}, []string{"iad1", "sfo1"}))
+}
+
+func TestMergeDirectives(t *testing.T) {
+	parent := ProjectConfig{
+		ServerlessFunctionRegions: []string{"fra1", "iad1"},
+		ProjectDomains: []ProjectDomain{
+			{Domain: "example.com"},
+			{Domain: "www.example.com", Redirect: "example.com"},
+		},
+		EnvironmentVariables: []ProjectEnvironmentVariable{
+			{Key: "API_URL", Value: "parent", Environment: []string{"production"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		child     ProjectConfig
+		regions   []string
+		domains   []ProjectDomain
+		variables []ProjectEnvironmentVariable
+	}{
+		{
+			name: "default",
+			child: ProjectConfig{
+				ServerlessFunctionRegions: []string{"iad1", "sfo1"},
+				ProjectDomains:            []ProjectDomain{{Domain: "example.com", GitBranch: "main"}},
+				EnvironmentVariables: []ProjectEnvironmentVariable{
+					{Key: "API_URL", Value: "child", Environment: []string{"production"}},
+				},
+			},
+			regions: []string{"fra1", "iad1", "sfo1"},
+			domains: []ProjectDomain{
+				{Domain: "example.com"},
+				{Domain: "www.example.com", Redirect: "example.com"},
+				{Domain: "example.com", GitBranch: "main"},
+			},
+			variables: []ProjectEnvironmentVariable{
+				{Key: "API_URL", Value: "parent", Environment: []string{"production"}},
+			},
+		},
+		{
+			name: "replace",
+			child: ProjectConfig{
+				ServerlessFunctionRegions:      []string{"sfo1"},
+				ServerlessFunctionRegionsMerge: "replace",
+				ProjectDomainsMerge:            "replace",
+				EnvironmentVariables: []ProjectEnvironmentVariable{
+					{Key: "DEBUG", Value: "true", Environment: []string{"preview"}},
+				},
+				EnvironmentVariablesMerge: "replace",
+			},
+			regions: []string{"sfo1"},
+			domains: nil,
+			variables: []ProjectEnvironmentVariable{
+				{Key: "DEBUG", Value: "true", Environment: []string{"preview"}},
+			},
+		},
+		{
+			name: "append",
+			child: ProjectConfig{
+				ServerlessFunctionRegions:      []string{"iad1"},
+				ServerlessFunctionRegionsMerge: "append",
+				ProjectDomains:                 []ProjectDomain{{Domain: "example.com"}},
+				ProjectDomainsMerge:            "append",
+				EnvironmentVariables: []ProjectEnvironmentVariable{
+					{Key: "API_URL", Value: "child", Environment: []string{"production"}},
+				},
+				EnvironmentVariablesMerge: "append",
+			},
+			regions: []string{"fra1", "iad1", "iad1"},
+			domains: []ProjectDomain{
+				{Domain: "example.com"},
+				{Domain: "www.example.com", Redirect: "example.com"},
+				{Domain: "example.com"},
+			},
+			variables: []ProjectEnvironmentVariable{
+				{Key: "API_URL", Value: "parent", Environment: []string{"production"}},
+				{Key: "API_URL", Value: "child", Environment: []string{"production"}},
+			},
+		},
+		{
+			name: "merge_by_key",
+			child: ProjectConfig{
+				ServerlessFunctionRegions:      []string{"iad1", "sfo1"},
+				ServerlessFunctionRegionsMerge: "merge_by_key",
+				ProjectDomains: []ProjectDomain{
+					{Domain: "example.com", GitBranch: "main"},
+					{Domain: "example.org"},
+				},
+				ProjectDomainsMerge: "merge_by_key",
+				EnvironmentVariables: []ProjectEnvironmentVariable{
+					{Key: "API_URL", Value: "child", Environment: []string{"production"}},
+				},
+				EnvironmentVariablesMerge: "merge_by_key",
+			},
+			regions: []string{"fra1", "iad1", "sfo1"},
+			domains: []ProjectDomain{
+				{Domain: "example.com", GitBranch: "main"},
+				{Domain: "www.example.com", Redirect: "example.com"},
+				{Domain: "example.org"},
+			},
+			variables: []ProjectEnvironmentVariable{
+				{Key: "API_URL", Value: "child", Environment: []string{"production"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.child.extendConfig(&parent)
+
+			assert.Equal(t, test.regions, result.ServerlessFunctionRegions)
+			assert.Equal(t, test.domains, result.ProjectDomains)
+			assert.Equal(t, test.variables, result.EnvironmentVariables)
+		})
+	}
+}
+
+// Every list of the project config needs a merge directive
+func TestMergeDirectiveFields(t *testing.T) {
+	fields := map[string]bool{}
+	typ := reflect.TypeOf(ProjectConfig{})
+	for i := 0; i < typ.NumField(); i++ {
+		fields[typ.Field(i).Tag.Get("mapstructure")] = true
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Type.Kind() == reflect.Slice && field.Tag.Get("merge") != mergeRuleIgnore {
+			tag := field.Tag.Get("mapstructure")
+			assert.True(t, fields[tag+"_merge"], "missing %s_merge directive", tag)
+		}
+	}
+}
+
+func TestMergeRules(t *testing.T) {
+	rules := []string{mergeRuleOverride, mergeRuleDeep, mergeRuleKeyed, mergeRuleDirective, mergeRuleIgnore}
+
+	seen := map[reflect.Type]bool{}
+	var check func(typ reflect.Type)
+	check = func(typ reflect.Type) {
+		if seen[typ] {
+			return
+		}
+		seen[typ] = true
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			rule, _, _ := strings.Cut(field.Tag.Get("merge"), "=")
+			if !assert.Contains(t, rules, rule, "field %s.%s has no merge rule", typ.Name(), field.Name) {
+				continue
+			}
+
+			switch rule {
+			case mergeRuleDeep:
+				switch field.Type.Kind() {
+				case reflect.Struct:
+					check(field.Type)
+				case reflect.Map:
+					if field.Type.Elem().Kind() == reflect.Struct {
+						check(field.Type.Elem())
+					}
+				default:
+					t.Errorf("field %s.%s cannot be merged deep", typ.Name(), field.Name)
+				}
+			case mergeRuleKeyed:
+				assert.NotPanics(t, func() {
+					mergeKeyed("", "", reflect.Zero(field.Type), reflect.Zero(field.Type))
+				}, "field %s.%s cannot be merged keyed", typ.Name(), field.Name)
+			}
+		}
+	}
+	check(reflect.TypeOf(VercelConfig{}))
+}
+
+func TestMergeStructs(t *testing.T) {
+	parent := &ProjectConfig{
+		Name: "parent",
+		PasswordProtection: PasswordProtection{
+			Password:       "secret",
+			DeploymentType: "standard_protection",
+		},
+		ProjectDomains: []ProjectDomain{{Domain: "example.com"}},
+	}
+	child := &ProjectConfig{
+		PasswordProtection: PasswordProtection{
+			DeploymentType: "all_deployments",
+		},
+		ProjectDomains:      []ProjectDomain{{Domain: "example.com", Redirect: "www.example.com"}},
+		ProjectDomainsMerge: mergeByKey,
+	}
+
+	result := mergeStructs(parent, child)
+
+	assert.Equal(t, "parent", result.Name)
+	assert.Equal(t, PasswordProtection{Password: "secret", DeploymentType: "all_deployments"}, result.PasswordProtection)
+	assert.Equal(t, []ProjectDomain{{Domain: "example.com", Redirect: "www.example.com"}}, result.ProjectDomains)
+	assert.Empty(t, result.ProjectDomainsMerge)
+	assert.Equal(t, "standard_protection", parent.PasswordProtection.DeploymentType)
+}
@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Matches the valid keys of a dotenv file
+var dotenvKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// A line of a dotenv file
+type dotenvVariable struct {
+	Key   string
+	Value string
+}
+
+// Reads the dotenv file at a path, relative to the directory of the
+// mach-composer configuration unless it is absolute
+func readDotenvFile(dir string, path string) ([]dotenvVariable, error) {
+	if !filepath.IsAbs(path) {
+		if dir == "" {
+			return nil, fmt.Errorf("%s: relative paths need the directory of the mach-composer configuration, which mach-composer does not pass to the plugin: set %s when running mach-composer or use an absolute path", path, configDirVariable)
+		}
+		path = filepath.Join(dir, path)
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDotenv(path, string(body))
+}
+
+// Parses the variables of a dotenv file. Lines hold KEY=VALUE pairs, which may
+// be prefixed with export. Values are unquoted, single quoted to be taken
+// literally or double quoted to support escape sequences, and quoted values may
+// span multiple lines. Comments start with a # at the start of a line or after
+// whitespace in an unquoted value. A key given twice takes the last value.
+func parseDotenv(name string, body string) ([]dotenvVariable, error) {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+
+	var result []dotenvVariable
+	index := map[string]int{}
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", name, lineNumber)
+		}
+		if !dotenvKey.MatchString(key) {
+			return nil, fmt.Errorf("%s:%d: invalid key %q", name, lineNumber, key)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`) || strings.HasPrefix(value, `'`):
+			quote := value[:1]
+			raw := value[1:]
+			for closingQuote(raw, quote) < 0 {
+				i++
+				if i == len(lines) {
+					return nil, fmt.Errorf("%s:%d: unterminated quoted value of %s", name, lineNumber, key)
+				}
+				raw += "\n" + lines[i]
+			}
+
+			end := closingQuote(raw, quote)
+			if rest := strings.TrimSpace(raw[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("%s:%d: unexpected %q after the quoted value of %s", name, i+1, rest, key)
+			}
+			value = raw[:end]
+			if quote == `"` {
+				value = unescapeDotenv(value)
+			}
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+
+		if existing, ok := index[key]; ok {
+			result[existing].Value = value
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, dotenvVariable{Key: key, Value: value})
+	}
+	return result, nil
+}
+
+// Returns the index of the quote closing a value, skipping escaped double
+// quotes
+func closingQuote(value string, quote string) int {
+	for i := 0; i < len(value); i++ {
+		switch {
+		case quote == `"` && value[i] == '\\':
+			i++
+		case value[i] == quote[0]:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDotenv(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(value)
+}
+
+// Adds the variables of the dotenv files of the project configs, including
+// those of the presets, projects and environment overrides, to their
+// environment variables
+func (c *VercelConfig) loadEnvironmentVariablesFiles(dir string) error {
+	if err := c.ProjectConfig.loadEnvironmentVariablesFiles(dir); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(c.Presets) {
+		preset := c.Presets[name]
+		if err := preset.loadEnvironmentVariablesFiles(dir); err != nil {
+			return err
+		}
+		c.Presets[name] = preset
+	}
+	for _, name := range sortedKeys(c.Projects) {
+		project := c.Projects[name]
+		if err := project.loadEnvironmentVariablesFiles(dir); err != nil {
+			return err
+		}
+		c.Projects[name] = project
+	}
+	for _, name := range sortedKeys(c.Environments) {
+		override := c.Environments[name]
+		if err := override.loadEnvironmentVariablesFiles(dir); err != nil {
+			return err
+		}
+		c.Environments[name] = override
+	}
+	return nil
+}
+
+// Merges the variables of the dotenv files into the environment variables.
+// Later files take precedence over earlier ones, and the inline variables
+// over all files. The inline variables keep their position, so the paths of
+// their values remain those of the configuration.
+func (c *ProjectConfig) loadEnvironmentVariablesFiles(dir string) error {
+	if len(c.EnvironmentVariablesFiles) == 0 {
+		return nil
+	}
+
+	var variables []ProjectEnvironmentVariable
+	for _, file := range c.EnvironmentVariablesFiles {
+		entries, err := readDotenvFile(dir, file.Path)
+		if err != nil {
+			return err
+		}
+
+		fileVariables := make([]ProjectEnvironmentVariable, 0, len(entries))
+		for _, entry := range entries {
+			fileVariables = append(fileVariables, ProjectEnvironmentVariable{
+				Key:         entry.Key,
+				Value:       entry.Value,
+				Environment: slices.Clone(file.Environment),
+				Source:      file.Path,
+			})
+		}
+		variables = MergeEnvironmentVariables(variables, fileVariables)
+	}
+
+	result := slices.Clone(c.EnvironmentVariables)
+	for _, env := range variables {
+		env.Environment = slices.DeleteFunc(env.Environment, func(environment string) bool {
+			return slices.ContainsFunc(c.EnvironmentVariables, func(inline ProjectEnvironmentVariable) bool {
+				return inline.Key == env.Key && (len(inline.Environment) == 0 && slices.Contains(knownEnvironments, environment) || slices.Contains(inline.Environment, environment))
+			})
+		})
+		if len(env.Environment) > 0 {
+			result = append(result, env)
+		}
+	}
+	c.EnvironmentVariables = result
+	return nil
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDotenv(t *testing.T) {
+	body := `# Settings of the storefront
+API_URL=https://api.example.com # the public API
+export DEBUG=false
+EMPTY=
+SINGLE='literal \n $value'
+DOUBLE="tab\tquote\" end"
+MULTILINE="first line
+second line"
+HASH=color#fff
+DEBUG=true
+`
+
+	result, err := parseDotenv(".env", body)
+	require.NoError(t, err)
+	assert.Equal(t, []dotenvVariable{
+		{Key: "API_URL", Value: "https://api.example.com"},
+		{Key: "DEBUG", Value: "true"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "SINGLE", Value: `literal \n $value`},
+		{Key: "DOUBLE", Value: "tab\tquote\" end"},
+		{Key: "MULTILINE", Value: "first line\nsecond line"},
+		{Key: "HASH", Value: "color#fff"},
+	}, result)
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  string
+	}{
+		{
+			name: "missing separator",
+			body: "API_URL=https://api.example.com\n\nDEBUG\n",
+			err:  ".env:3: expected KEY=VALUE",
+		},
+		{
+			name: "invalid key",
+			body: "API URL=https://api.example.com\n",
+			err:  `.env:1: invalid key "API URL"`,
+		},
+		{
+			name: "unterminated quote",
+			body: "A=1\nB=\"first line\nsecond line\n",
+			err:  ".env:2: unterminated quoted value of B",
+		},
+		{
+			name: "text after quoted value",
+			body: "A='value' trailing\n",
+			err:  `.env:1: unexpected "trailing" after the quoted value of A`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseDotenv(".env", tt.body)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
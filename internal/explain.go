@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExplainOptions describes the component for which Explain reports the
+// origin of its configuration.
+type ExplainOptions struct {
+	// Path of the mach-composer configuration file
+	File string
+	// Environment to apply the overrides of, defaults to the environment of
+	// the configuration file
+	Environment string
+	Site        string
+	Component   string
+}
+
+// The parts of a mach-composer configuration file which hold the vercel
+// configuration
+type machConfig struct {
+	Global struct {
+		Environment string         `yaml:"environment"`
+		Vercel      map[string]any `yaml:"vercel"`
+	} `yaml:"global"`
+	Sites []struct {
+		Identifier string         `yaml:"identifier"`
+		Vercel     map[string]any `yaml:"vercel"`
+		Components []struct {
+			Name   string         `yaml:"name"`
+			Vercel map[string]any `yaml:"vercel"`
+		} `yaml:"components"`
+	} `yaml:"sites"`
+}
+
+// Explain returns every effective field of a component, with the level that
+// set it and the values of the parent levels it overrode.
+func Explain(opts ExplainOptions) (string, error) {
+	p, config, err := loadConfigFile(opts.File, opts.Environment)
+	if err != nil {
+		return "", err
+	}
+
+	found := false
+	for _, site := range config.Sites {
+		for _, component := range site.Components {
+			if site.Identifier == opts.Site && component.Name == opts.Component {
+				found = true
+			}
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("component %s not found in site %s", opts.Component, opts.Site)
+	}
+
+	entries, err := p.explain(opts.Site, opts.Component)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		overrides := entry.Overridden
+		if entry.Unset {
+			fmt.Fprintf(&sb, "%s unset at %s (was %s from %s)\n", entry.Path, entry.Level, overrides[0].Value, overrides[0].Level)
+			overrides = overrides[1:]
+		} else {
+			fmt.Fprintf(&sb, "%s = %s (%s)\n", entry.Path, entry.Value, entry.Level)
+		}
+		if entry.Template != "" {
+			fmt.Fprintf(&sb, "    evaluated from %s\n", entry.Template)
+		}
+		for _, overridden := range overrides {
+			fmt.Fprintf(&sb, "    overrides %s (%s)\n", overridden.Value, overridden.Level)
+		}
+	}
+	return sb.String(), nil
+}
+
+// Reads the vercel configuration of every level of a mach-composer
+// configuration file into a plugin, for use outside of mach-composer. The
+// environment defaults to the environment of the configuration file.
+func loadConfigFile(file string, environment string) (*VercelPlugin, *machConfig, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(body, &node); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", file, err)
+	}
+	resolveUnsetTags(&node)
+
+	var config machConfig
+	if err := node.Decode(&config); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	p := &VercelPlugin{
+		environment: environment,
+		siteConfigs: map[string]*VercelConfig{},
+		configDir:   filepath.Dir(file),
+	}
+	if p.environment == "" {
+		p.environment = config.Global.Environment
+	}
+
+	if config.Global.Vercel != nil {
+		if err := p.SetGlobalConfig(config.Global.Vercel); err != nil {
+			return nil, nil, fmt.Errorf("global.vercel: %w", err)
+		}
+	}
+
+	for _, site := range config.Sites {
+		if site.Vercel != nil {
+			if err := p.SetSiteConfig(site.Identifier, site.Vercel); err != nil {
+				return nil, nil, fmt.Errorf("sites[%s].vercel: %w", site.Identifier, err)
+			}
+		}
+		for _, component := range site.Components {
+			if component.Vercel != nil {
+				if err := p.SetSiteComponentConfig(site.Identifier, component.Name, component.Vercel); err != nil {
+					return nil, nil, fmt.Errorf("sites[%s].components[%s].vercel: %w", site.Identifier, component.Name, err)
+				}
+			}
+		}
+	}
+	return p, &config, nil
+}
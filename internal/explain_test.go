@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvenance(t *testing.T) {
+	plugin := &VercelPlugin{environment: "production", siteConfigs: map[string]*VercelConfig{}}
+
+	require.NoError(t, plugin.SetGlobalConfig(map[string]any{
+		"team_id": "team-a",
+		"project_config": map[string]any{
+			"framework":                    "react",
+			"build_command":                "npm run build",
+			"root_directory":               "web",
+			"manual_production_deployment": true,
+			"environment_variables": []any{
+				map[string]any{"key": "API_URL", "value": "global", "environment": []any{"production"}},
+			},
+		},
+	}))
+	require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+		"project_config": map[string]any{
+			"framework":      "nextjs",
+			"root_directory": "!unset",
+			"environment_variables": []any{
+				map[string]any{"key": "API_URL", "value": "site", "environment": []any{"preview"}},
+			},
+		},
+		"environments": map[string]any{
+			"production": map[string]any{
+				"project_config": map[string]any{"node_version": "22.x"},
+			},
+		},
+	}))
+	require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"project_config": map[string]any{
+			"framework":                    "nextjs",
+			"build_command":                "!unset",
+			"root_directory":               "apps/web",
+			"manual_production_deployment": "!unset",
+		},
+	}))
+
+	entries, err := plugin.explain("my-site", "my-component")
+	require.NoError(t, err)
+	result := map[string]provenance{}
+	for _, entry := range entries {
+		result[entry.Path] = entry
+	}
+
+	assert.Equal(t, provenance{
+		Path:  "project_config.framework",
+		Value: `"nextjs"`,
+		Level: "component",
+		Overridden: []overriddenValue{
+			{Level: "site", Value: `"nextjs"`},
+			{Level: "global", Value: `"react"`},
+		},
+	}, result["project_config.framework"])
+	assert.Equal(t, provenance{
+		Path:       "project_config.build_command",
+		Level:      "component",
+		Unset:      true,
+		Overridden: []overriddenValue{{Level: "global", Value: `"npm run build"`}},
+	}, result["project_config.build_command"])
+	assert.Equal(t, provenance{
+		Path:  "project_config.root_directory",
+		Value: `"apps/web"`,
+		Level: "component",
+		Overridden: []overriddenValue{
+			{Level: "site", Value: "unset"},
+			{Level: "global", Value: `"web"`},
+		},
+	}, result["project_config.root_directory"])
+	assert.Equal(t, provenance{
+		Path:  "project_config.manual_production_deployment",
+		Value: "false",
+		Level: "default",
+		Overridden: []overriddenValue{
+			{Level: "component", Value: "unset"},
+			{Level: "global", Value: "true"},
+		},
+	}, result["project_config.manual_production_deployment"])
+	assert.Equal(t, "site, environments.production", result["project_config.node_version"].Level)
+	assert.Equal(t, "global", result["team_id"].Level)
+	assert.Equal(t, "global", result["project_config.environment_variables[API_URL].production"].Level)
+	assert.Equal(t, "site", result["project_config.environment_variables[API_URL].preview"].Level)
+	assert.Equal(t, provenance{
+		Path:  "project_config.vercel_authentication.deployment_type",
+		Value: `"standard_protection"`,
+		Level: "default",
+	}, result["project_config.vercel_authentication.deployment_type"])
+}
+
+func TestExplain(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.yml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+global:
+  environment: test
+  vercel:
+    team_id: team-a
+sites:
+  - identifier: my-site
+    vercel:
+      team_id: team-b
+      project_config:
+        build_command: npm run build
+        root_directory: apps/web
+    components:
+      - name: my-component
+        vercel:
+          project_config:
+            name: my-project
+            build_command: "!unset"
+            root_directory: !unset
+            domains:
+              - domain: "{{ component }}.example.com"
+                redirect: "www.{{ site }}.example.com"
+`), 0o644))
+
+	result, err := Explain(ExplainOptions{File: file, Site: "my-site", Component: "my-component"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "team_id = \"team-b\" (site)\n    overrides \"team-a\" (global)\n")
+	assert.Contains(t, result, "project_config.name = \"my-project\" (component)\n")
+	assert.Contains(t, result, "project_config.domains[{{ component }}.example.com].domain = \"my-component.example.com\" (component)\n"+
+		"    evaluated from \"{{ component }}.example.com\"\n")
+	assert.Contains(t, result, "project_config.domains[{{ component }}.example.com].redirect = \"www.my-site.example.com\" (component)\n"+
+		"    evaluated from \"www.{{ site }}.example.com\"\n")
+	assert.Contains(t, result, "project_config.build_command unset at component (was \"npm run build\" from site)\n")
+	assert.Contains(t, result, "project_config.root_directory unset at component (was \"apps/web\" from site)\n")
+
+	_, err = Explain(ExplainOptions{File: file, Site: "my-site", Component: "other"})
+	assert.EqualError(t, err, "component other not found in site my-site")
+}
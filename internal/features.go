@@ -0,0 +1,215 @@
+package internal
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mach-composer/mach-composer-plugin-helpers/helpers"
+)
+
+// The minimum vercel/vercel provider version per configuration field. A key
+// is the path of the field, optionally followed by `=value` when only that
+// value needs a newer provider. Every entry names the provider resource or
+// attribute the field depends on and the release which added it. Fields
+// which the provider does not manage, such as the routes, crons and
+// functions of the vercel.json, have no entry.
+//
+//go:embed features.json
+var featureMatrixData []byte
+
+type feature struct {
+	Version  string `json:"version"`
+	Requires string `json:"requires"`
+	Source   string `json:"source"`
+}
+
+type featureMatrix map[string]feature
+
+func loadFeatureMatrix() featureMatrix {
+	var result featureMatrix
+	if err := json.Unmarshal(featureMatrixData, &result); err != nil {
+		panic(err)
+	}
+	return result
+}
+
+var features = loadFeatureMatrix()
+
+// Returns an error listing the configured fields which are not available in
+// every provider version the constraint allows.
+func checkProviderFeatures(provider string, cfg *VercelConfig) error {
+	constraint := helpers.VersionConstraint(provider)
+	minimum, err := minimumVersion(constraint)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(features))
+	for key := range features {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// The fields which are set, by key of the feature matrix. Fields of the
+	// project config are checked for every project of the component.
+	var used []usedFeature
+	for _, key := range keys {
+		path, value, _ := strings.Cut(key, "=")
+		field, ok := strings.CutPrefix(path, "project_config.")
+		if !ok {
+			if fieldIsSet(reflect.ValueOf(*cfg), strings.Split(path, "."), value) {
+				used = append(used, usedFeature{key, featureName(path, value)})
+			}
+			continue
+		}
+
+		for _, project := range cfg.projects() {
+			prefix := "project_config"
+			if project.Name != "" {
+				prefix = "projects." + project.Name
+			}
+			if fieldIsSet(reflect.ValueOf(project.Config.ProjectConfig), strings.Split(field, "."), value) {
+				used = append(used, usedFeature{key, featureName(prefix+"."+field, value)})
+			}
+		}
+	}
+	sort.SliceStable(used, func(i, j int) bool { return used[i].name < used[j].name })
+
+	var unsupported []string
+	required := minimum
+	for _, u := range used {
+		version, err := parseVersion(features[u.key].Version)
+		if err != nil {
+			return err
+		}
+		if compareVersions(version, minimum) <= 0 {
+			continue
+		}
+
+		unsupported = append(unsupported, fmt.Sprintf("%s (requires %s)", u.name, version))
+		if compareVersions(version, required) > 0 {
+			required = version
+		}
+	}
+
+	if len(unsupported) > 0 {
+		return fmt.Errorf(
+			"vercel provider %q does not support %s, use at least version %s",
+			constraint, strings.Join(unsupported, ", "), required)
+	}
+	return nil
+}
+
+// A field of the feature matrix which is set, with the name it is reported as
+type usedFeature struct {
+	key  string
+	name string
+}
+
+// Returns the name of a field in the errors, followed by the value when only
+// that value needs a newer provider
+func featureName(path string, value string) string {
+	if value != "" {
+		return path + " " + value
+	}
+	return path
+}
+
+// Reports whether the field at the path is set, or equals the value when it
+// is given. A path through a list matches when any of its items match.
+func fieldIsSet(v reflect.Value, path []string, value string) bool {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+
+	if len(path) == 0 {
+		if value != "" {
+			return fmt.Sprint(v.Interface()) == value
+		}
+		return !v.IsZero()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if fieldIsSet(v.Index(i), path, value) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Tag.Get("mapstructure") == path[0] {
+				return fieldIsSet(v.Field(i), path[1:], value)
+			}
+		}
+	}
+	return false
+}
+
+type version [3]int
+
+func (v version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+func parseVersion(value string) (version, error) {
+	var result version
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(value), "v"), ".")
+	if len(parts) > len(result) {
+		return result, fmt.Errorf("invalid provider version %q", value)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return result, fmt.Errorf("invalid provider version %q", value)
+		}
+		result[i] = n
+	}
+	return result, nil
+}
+
+func compareVersions(a, b version) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Returns the lowest provider version allowed by a terraform version
+// constraint. Constraints without a lower bound allow any version.
+func minimumVersion(constraint string) (version, error) {
+	var result version
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		value := strings.TrimLeft(part, "!=<>~ ")
+		operator := strings.TrimSpace(strings.TrimSuffix(part, value))
+
+		switch operator {
+		case "", "=", "~>", ">=", ">":
+			v, err := parseVersion(value)
+			if err != nil {
+				return result, err
+			}
+			if operator == ">" {
+				v[2]++
+			}
+			if compareVersions(v, result) > 0 {
+				result = v
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimumVersion(t *testing.T) {
+	tests := []struct {
+		constraint string
+		expected   string
+	}{
+		{constraint: "~> 1.12.0", expected: "1.12.0"},
+		{constraint: ">= 2.1", expected: "2.1.0"},
+		{constraint: "= 3.3.0", expected: "3.3.0"},
+		{constraint: "> 3.3.0", expected: "3.3.1"},
+		{constraint: ">= 1.0.0, < 4.0.0", expected: "1.0.0"},
+		{constraint: "< 4.0.0", expected: "0.0.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.constraint, func(t *testing.T) {
+			result, err := minimumVersion(test.constraint)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result.String())
+		})
+	}
+
+	_, err := minimumVersion("~> latest")
+	assert.ErrorContains(t, err, "invalid provider version")
+}
+
+func TestCheckProviderFeatures(t *testing.T) {
+	cfg := &VercelConfig{
+		ProjectConfig: ProjectConfig{
+			EnvironmentVariablesMode: "bulk",
+			RollingRelease: RollingRelease{
+				Stages: []RollingReleaseStage{{TargetPercentage: 100}},
+			},
+		},
+		Projects: map[string]ProjectConfig{
+			"storybook": {ServerlessFunctionRegions: []string{"fra1"}},
+		},
+	}
+
+	t.Run("fields supported by the constraint", func(t *testing.T) {
+		assert.NoError(t, checkProviderFeatures("3.3.0", cfg))
+		assert.NoError(t, checkProviderFeatures(">= 3.4", cfg))
+	})
+
+	t.Run("fields requiring a newer provider", func(t *testing.T) {
+		err := checkProviderFeatures("1.12.0", cfg)
+		assert.EqualError(t, err, `vercel provider "~> 1.12.0" does not support `+
+			`projects.storybook.rolling_release (requires 3.3.0), `+
+			`projects.storybook.serverless_function_regions (requires 2.10.0), `+
+			`use at least version 3.3.0`)
+	})
+
+	t.Run("values requiring a newer provider", func(t *testing.T) {
+		cfg := &VercelConfig{ProjectConfig: ProjectConfig{EnvironmentVariablesMode: "bulk"}}
+		assert.ErrorContains(t, checkProviderFeatures("1.10.0", cfg), "project_config.environment_variables_mode bulk (requires 1.11.0)")
+
+		cfg.ProjectConfig.EnvironmentVariablesMode = "inline"
+		assert.NoError(t, checkProviderFeatures("1.10.0", cfg))
+	})
+
+	t.Run("modes requiring a newer provider", func(t *testing.T) {
+		cfg := &VercelConfig{Mode: modeManaged}
+		assert.EqualError(t, checkProviderFeatures("0.15.0", cfg),
+			`vercel provider "~> 0.15.0" does not support mode managed (requires 1.0.0), use at least version 1.0.0`)
+		assert.NoError(t, checkProviderFeatures("1.0.0", cfg))
+	})
+}
+
+func TestFeatureMatrix(t *testing.T) {
+	for key, f := range features {
+		t.Run(key, func(t *testing.T) {
+			_, err := parseVersion(f.Version)
+			require.NoError(t, err)
+			assert.NotEmpty(t, f.Requires)
+			assert.Equal(t, "https://github.com/vercel/terraform-provider-vercel/releases/tag/v"+f.Version, f.Source)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Returns the environment variable groups of the global config, with the
+// overrides of the active environment applied
+func (p *VercelPlugin) environmentVariableGroups() map[string][]ProjectEnvironmentVariable {
+	if p.globalConfig == nil {
+		return nil
+	}
+	return p.globalConfig.forEnvironment(p.environment).EnvironmentVariableGroups
+}
+
+// Returns a copy of the config with the variables of the groups used by its
+// project configs added to their environment variables
+func (c *VercelConfig) expandGroups(groups map[string][]ProjectEnvironmentVariable) *VercelConfig {
+	if c == nil {
+		return nil
+	}
+
+	cfg := c.clone()
+	cfg.ProjectConfig.expandGroups(groups)
+	if len(c.Projects) > 0 {
+		cfg.Projects = make(map[string]ProjectConfig, len(c.Projects))
+		for name, project := range c.Projects {
+			project.expandGroups(groups)
+			cfg.Projects[name] = project
+		}
+	}
+	return cfg
+}
+
+// Adds the variables of the groups in use_groups to the environment variables.
+// Groups are expanded in the order in which they are listed, with later groups
+// taking precedence over earlier ones and the own variables over all groups.
+// Unknown groups are skipped, validateConfig reports them.
+func (c *ProjectConfig) expandGroups(groups map[string][]ProjectEnvironmentVariable) {
+	if len(c.UseGroups) == 0 {
+		return
+	}
+
+	var variables []ProjectEnvironmentVariable
+	for _, name := range c.UseGroups {
+		if group, ok := groups[name]; ok {
+			variables = MergeEnvironmentVariables(variables, group)
+		}
+	}
+	c.EnvironmentVariables = MergeEnvironmentVariables(variables, c.EnvironmentVariables)
+}
+
+// Returns a line for every group used by the project configs of the levels
+// which is not defined in the global config, naming the levels which use it
+func (p *VercelPlugin) unknownGroups(site string, component string) []string {
+	groups := p.environmentVariableGroups()
+
+	var paths []string
+	origins := map[string][]string{}
+	check := func(level string, path string, config ProjectConfig) {
+		for _, name := range config.UseGroups {
+			if _, ok := groups[name]; ok {
+				continue
+			}
+			key := fmt.Sprintf("%s.use_groups: unknown environment variable group %s", path, name)
+			if _, ok := origins[key]; !ok {
+				paths = append(paths, key)
+			}
+			if !slices.Contains(origins[key], level) {
+				origins[key] = append(origins[key], level)
+			}
+		}
+	}
+
+	for _, level := range p.getLevels(site, component) {
+		check(level.name, "project_config", level.config.ProjectConfig)
+		for _, name := range sortedKeys(level.config.Projects) {
+			check(level.name, "projects."+name, level.config.Projects[name])
+		}
+	}
+
+	lines := make([]string, 0, len(paths))
+	for _, path := range paths {
+		lines = append(lines, fmt.Sprintf("  - %s (from %s)", path, strings.Join(origins[path], ", ")))
+	}
+	return lines
+}
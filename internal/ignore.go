@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"strings"
+)
+
+// Returns the ignore_command of the project: the configured command, or else
+// the command generated from the ignore settings
+func (c *ProjectConfig) EffectiveIgnoreCommand() string {
+	if c.IgnoreCommand != "" {
+		return c.IgnoreCommand
+	}
+	return c.Ignore.command()
+}
+
+// Generates the ignored build step command. Vercel skips the build when the
+// command exits with 0, which is the case on one of the skipped branches, or
+// when neither the turbo workspace nor the paths changed since the previous
+// commit. Without any setting the command is empty.
+func (c *IgnoreConfig) command() string {
+	var checks []string
+	if c.TurboWorkspace != "" {
+		checks = append(checks, "npx turbo-ignore "+shellQuote(c.TurboWorkspace))
+	}
+	if len(c.Paths) > 0 {
+		paths := make([]string, 0, len(c.Paths))
+		for _, path := range c.Paths {
+			paths = append(paths, shellQuote(path))
+		}
+		checks = append(checks, "git diff HEAD^ HEAD --quiet -- "+strings.Join(paths, " "))
+	}
+
+	var conditions []string
+	for _, branch := range c.SkipBranches {
+		conditions = append(conditions, `[ "$VERCEL_GIT_COMMIT_REF" = `+shellQuote(branch)+` ]`)
+	}
+	if len(checks) > 0 {
+		check := strings.Join(checks, " && ")
+		if len(checks) > 1 && len(conditions) > 0 {
+			check = "(" + check + ")"
+		}
+		conditions = append(conditions, check)
+	}
+	return strings.Join(conditions, " || ")
+}
+
+// Quotes a value as a single argument of a shell command
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mach-composer/mach-composer-plugin-helpers/helpers"
+	"github.com/mach-composer/mach-composer-plugin-sdk/schema"
+)
+
+// The modes in which the plugin can render a component. In the default
+// variables mode the component module creates the vercel_project itself, in
+// managed mode the plugin renders the resources and passes their outputs.
+const (
+	modeVariables = "variables"
+	modeManaged   = "managed"
+)
+
+const managedResourcesTemplate = `
+	resource "vercel_project" "{{ .ResourceName }}" {
+		{{ if .Provider }}provider = {{ .Provider }}{{ end }}
+		{{ renderProperty "name" .ProjectConfig.Name }}
+		{{ renderOptionalProperty "team_id" .TeamID }}
+		{{ renderOptionalProperty "framework" .ProjectConfig.Framework }}
+		{{ renderOptionalProperty "build_command" .ProjectConfig.BuildCommand }}
+		{{ renderOptionalProperty "ignore_command" .ProjectConfig.EffectiveIgnoreCommand }}
+		{{ renderOptionalProperty "root_directory" .ProjectConfig.RootDirectory }}
+		{{ renderOptionalProperty "node_version" .ProjectConfig.NodeVersion }}
+		{{ renderOptionalProperty "serverless_function_region" .ProjectConfig.ServerlessFunctionRegion }}
+		{{ if .ProjectConfig.ServerlessFunctionRegions }}resource_config = {
+			{{ renderProperty "function_default_regions" .ProjectConfig.ServerlessFunctionRegions }}
+		}{{ end }}
+		{{ renderProperty "protection_bypass_for_automation" .ProjectConfig.ProtectionBypassForAutomation }}
+		{{ with .ProjectConfig.GitRepository }}{{ if .Repo }}git_repository = {
+			{{ renderProperty "type" .Type }}
+			{{ renderProperty "repo" .Repo }}
+			{{ renderOptionalProperty "production_branch" .ProductionBranch }}
+		}{{ end }}{{ end }}
+		vercel_authentication = {
+			{{ renderProperty "deployment_type" .ProjectConfig.VercelAuthentication.DeploymentType }}
+		}
+		{{ with .ProjectConfig.PasswordProtection }}{{ if .Password }}password_protection = {
+			{{ renderProperty "password" .Password }}
+			{{ renderProperty "deployment_type" .DeploymentType }}
+		}{{ end }}{{ end }}
+		{{ if eq .ProjectConfig.EnvironmentVariablesMode "inline" }}environment = [{{ range .ProjectConfig.EnvironmentVariables }}
+			{
+				{{ renderProperty "key" .Key }}
+				{{ .DisplayValue }}
+				{{ .DisplayTargets }}
+			},{{ end }}
+		]{{ end }}
+
+		lifecycle {
+			# never accidentally destroy this resource
+			prevent_destroy = true
+		}
+	}
+	{{ if eq .ProjectConfig.EnvironmentVariablesMode "bulk" }}
+	resource "vercel_project_environment_variables" "{{ .ResourceName }}" {
+		{{ if .Provider }}provider = {{ .Provider }}{{ end }}
+		project_id = vercel_project.{{ .ResourceName }}.id
+		{{ renderOptionalProperty "team_id" .TeamID }}
+		variables = [{{ range .ProjectConfig.EnvironmentVariables }}
+			{
+				{{ renderProperty "key" .Key }}
+				{{ .DisplayValue }}
+				{{ .DisplayTargets }}
+			},{{ end }}
+		]
+	}
+	{{ end }}{{ if .ProjectConfig.ProjectDomains }}
+	resource "vercel_project_domain" "{{ .ResourceName }}" {
+		{{ if .Provider }}provider = {{ .Provider }}{{ end }}
+		{{ .RenderDomainsForEach }}
+		project_id = vercel_project.{{ .ResourceName }}.id
+		{{ renderOptionalProperty "team_id" .TeamID }}
+		domain = each.key
+		git_branch = each.value.git_branch
+		redirect = each.value.redirect
+		redirect_status_code = each.value.redirect_status_code
+	}
+	{{ end }}{{ with .ProjectConfig.RollingRelease }}{{ if .Stages }}
+	resource "vercel_project_rolling_release" "{{ $.ResourceName }}" {
+		{{ if $.Provider }}provider = {{ $.Provider }}{{ end }}
+		project_id = vercel_project.{{ $.ResourceName }}.id
+		{{ renderOptionalProperty "team_id" $.TeamID }}
+		rolling_release = {
+			enabled = true
+			{{ renderProperty "advancement_type" .AdvancementType }}
+			stages = [{{ range .Stages }}
+				{
+					{{ renderProperty "target_percentage" .TargetPercentage }}
+					{{ if .Duration }}{{ renderProperty "duration" .Duration }}{{ end }}
+				},{{ end }}
+			]
+		}
+	}
+	{{ end }}{{ end }}
+`
+
+// Returns the for_each of the vercel_project_domain resource, keyed by domain
+// so the resource addresses do not depend on how the domains are slugified.
+// Unset fields are null so every domain has the same attributes.
+func (d componentData) RenderDomainsForEach() string {
+	domains := map[string]map[string]any{}
+	for _, domain := range d.ProjectConfig.ProjectDomains {
+		domains[domain.Domain] = map[string]any{
+			"git_branch":           optional(domain.GitBranch),
+			"redirect":             optional(domain.Redirect),
+			"redirect_status_code": optional(domain.RedirectStatusCode),
+		}
+	}
+	return renderHCL("for_each", domains)
+}
+
+// Returns nil for the zero value, which is rendered as null
+func optional[T comparable](value T) any {
+	var zero T
+	if value == zero {
+		return nil
+	}
+	return value
+}
+
+// Renders the vercel_project and its related resources for all projects of a
+// component and the variables to pass their outputs to the component module.
+func renderManagedComponent(component string, projects []namedProject, alias string) (*schema.ComponentSchema, error) {
+	var resources, vars strings.Builder
+	for _, project := range projects {
+		data := componentData{
+			VercelConfig: project.Config,
+			Prefix:       projectPrefix(project.Name),
+			ResourceName: helpers.Slugify(component),
+		}
+		if alias != "" {
+			data.Provider = "vercel." + alias
+		}
+		if project.Name != "" {
+			data.ResourceName += "_" + helpers.Slugify(project.Name)
+		}
+
+		if data.ProjectConfig.Name == "" {
+			if project.Name != "" {
+				return nil, fmt.Errorf("projects.%s.name is required for component %s in managed mode", project.Name, component)
+			}
+			return nil, fmt.Errorf("project_config.name is required for component %s in managed mode", component)
+		}
+
+		result, err := helpers.RenderGoTemplate(managedResourcesTemplate, data)
+		if err != nil {
+			return nil, err
+		}
+		resources.WriteString(result)
+
+		result, err = helpers.RenderGoTemplate(variablesTemplate(managedVariables), data)
+		if err != nil {
+			return nil, err
+		}
+		vars.WriteString(result)
+	}
+
+	return &schema.ComponentSchema{
+		Resources: resources.String(),
+		Variables: vars.String(),
+	}, nil
+}
@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// The strategies of the `<field>_merge` directives, which define how the list
+// of a level is merged into the list of its parent level. Without a directive
+// each list keeps its original merge behavior.
+const (
+	// The list of the level replaces the list of the parent, also when empty
+	mergeReplace = "replace"
+	// The list of the level is appended to the list of the parent
+	mergeAppend = "append"
+	// Items of the level replace the items of the parent with the same key,
+	// other items are appended
+	mergeByKey = "merge_by_key"
+)
+
+// Merges the child list into the parent list with the given strategy
+func mergeList[T any](strategy string, parent []T, child []T, key func(T) string) []T {
+	switch strategy {
+	case mergeReplace:
+		return slices.Clone(child)
+	case mergeByKey:
+		result := slices.Clone(parent)
+		for _, item := range child {
+			i := slices.IndexFunc(result, func(o T) bool { return key(o) == key(item) })
+			if i >= 0 {
+				result[i] = item
+			} else {
+				result = append(result, item)
+			}
+		}
+		return result
+	default:
+		return append(slices.Clone(parent), child...)
+	}
+}
+
+// Merges lists of strings. By default the values of the child which are not
+// part of the parent are appended.
+func mergeStringList(strategy string, parent []string, child []string) []string {
+	if strategy == "" {
+		return mergeStrings(parent, child)
+	}
+	return mergeList(strategy, parent, child, func(value string) string { return value })
+}
+
+// Merges environment variables, keyed by key and environment. By default the
+// value of the parent is kept when both levels set the same key and
+// environment, with merge_by_key the value of the child is used.
+func mergeEnvironmentVariableList(strategy string, parent []ProjectEnvironmentVariable, child []ProjectEnvironmentVariable) []ProjectEnvironmentVariable {
+	switch strategy {
+	case "":
+		return MergeEnvironmentVariables(child, parent)
+	case mergeByKey:
+		return MergeEnvironmentVariables(parent, child)
+	default:
+		return mergeList(strategy, parent, child, nil)
+	}
+}
+
+// Merges lists which have no behavior of their own, such as the routes and
+// crons of the vercel.json. By default they are merged by key.
+func mergeKeyedList[T any](strategy string, parent []T, child []T, key func(T) string) []T {
+	if strategy == "" {
+		strategy = mergeByKey
+	}
+	return mergeList(strategy, parent, child, key)
+}
+
+// Merges domains. By default the domains of the child are appended, except
+// those which are identical to a domain of the parent.
+func mergeDomainList(strategy string, parent []ProjectDomain, child []ProjectDomain, key func(ProjectDomain) string) []ProjectDomain {
+	if strategy == "" {
+		result := slices.Clone(parent)
+		for _, domain := range child {
+			if !slices.Contains(parent, domain) {
+				result = append(result, domain)
+			}
+		}
+		return result
+	}
+	return mergeList(strategy, parent, child, key)
+}
+
+// The merge rules of the configuration fields, set with the `merge` struct tag:
+//
+//   - override: the value of the child replaces the value of the parent when
+//     it is set
+//   - deep: structs are merged field by field and maps entry by entry, unless
+//     the child is empty
+//   - keyed=<field>: lists merged with the `<field>_merge` directive of the
+//     list, where merge_by_key matches the items on the given field or on the
+//     value itself without a field
+//   - directive: merge directives, which only apply to their own level
+//   - ignore: fields which are not inherited
+//
+// Fields which the child clears through its `unset` field are cleared in the
+// result.
+const (
+	mergeRuleOverride  = "override"
+	mergeRuleDeep      = "deep"
+	mergeRuleKeyed     = "keyed"
+	mergeRuleDirective = "directive"
+	mergeRuleIgnore    = "ignore"
+)
+
+// Returns a copy of the parent with the child merged into it according to the
+// merge rules of the fields
+func mergeStructs[T any](parent *T, child *T) *T {
+	if parent == nil {
+		return child
+	}
+	result := mergeStruct(reflect.ValueOf(parent).Elem(), reflect.ValueOf(child).Elem()).Interface().(T)
+	return &result
+}
+
+func mergeStruct(parent reflect.Value, child reflect.Value) reflect.Value {
+	typ := parent.Type()
+	result := reflect.New(typ).Elem()
+	result.Set(parent)
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		rule, key, _ := strings.Cut(field.Tag.Get("merge"), "=")
+		p, c := parent.Field(i), child.Field(i)
+
+		switch rule {
+		case mergeRuleOverride:
+			if isSet(c) {
+				result.Field(i).Set(c)
+			}
+		case mergeRuleDeep:
+			result.Field(i).Set(mergeDeep(p, c))
+		case mergeRuleKeyed:
+			directive := fieldByTag(child, field.Tag.Get("mapstructure")+"_merge").String()
+			result.Field(i).Set(mergeKeyed(directive, key, p, c))
+		case mergeRuleDirective, mergeRuleIgnore:
+			result.Field(i).SetZero()
+		default:
+			panic(fmt.Sprintf("field %s.%s has no merge rule", typ.Name(), field.Name))
+		}
+	}
+
+	if unset, ok := child.FieldByName("Unset").Interface().(unsetFields); ok {
+		unset.clear(result.Addr().Interface())
+		parentUnset := parent.FieldByName("Unset").Interface().(unsetFields)
+		result.FieldByName("Unset").Set(reflect.ValueOf(unset.inherit(parentUnset, child.Interface())))
+	}
+	return result
+}
+
+func mergeDeep(parent reflect.Value, child reflect.Value) reflect.Value {
+	switch parent.Kind() {
+	case reflect.Struct:
+		if child.IsZero() {
+			return parent
+		}
+		return mergeStruct(parent, child)
+	case reflect.Map:
+		if child.Len() == 0 {
+			return parent
+		}
+		result := reflect.MakeMapWithSize(parent.Type(), parent.Len()+child.Len())
+		for _, key := range parent.MapKeys() {
+			result.SetMapIndex(key, parent.MapIndex(key))
+		}
+		for _, key := range child.MapKeys() {
+			// Entries which are not structs or maps are replaced as a whole
+			value := child.MapIndex(key)
+			if p := parent.MapIndex(key); p.IsValid() && (p.Kind() == reflect.Struct || p.Kind() == reflect.Map) {
+				value = mergeDeep(p, value)
+			}
+			result.SetMapIndex(key, value)
+		}
+		return result
+	}
+	panic(fmt.Sprintf("deep merge is not supported for %s", parent.Type()))
+}
+
+func mergeKeyed(strategy string, key string, parent reflect.Value, child reflect.Value) reflect.Value {
+	switch p := parent.Interface().(type) {
+	case []ProjectEnvironmentVariable:
+		return reflect.ValueOf(mergeEnvironmentVariableList(strategy, p, child.Interface().([]ProjectEnvironmentVariable)))
+	case []ProjectDomain:
+		return reflect.ValueOf(mergeDomainList(strategy, p, child.Interface().([]ProjectDomain), itemKey[ProjectDomain](key)))
+	case []string:
+		return reflect.ValueOf(mergeStringList(strategy, p, child.Interface().([]string)))
+	case []RouteHeaders:
+		return reflect.ValueOf(mergeKeyedList(strategy, p, child.Interface().([]RouteHeaders), itemKey[RouteHeaders](key)))
+	case []Redirect:
+		return reflect.ValueOf(mergeKeyedList(strategy, p, child.Interface().([]Redirect), itemKey[Redirect](key)))
+	case []Rewrite:
+		return reflect.ValueOf(mergeKeyedList(strategy, p, child.Interface().([]Rewrite), itemKey[Rewrite](key)))
+	case []Cron:
+		return reflect.ValueOf(mergeKeyedList(strategy, p, child.Interface().([]Cron), itemKey[Cron](key)))
+	}
+	panic(fmt.Sprintf("keyed merge is not supported for %s", parent.Type()))
+}
+
+// Returns a function which returns the value of the field with the tag as the
+// key of a list item
+func itemKey[T any](tag string) func(T) string {
+	return func(item T) string {
+		return fmt.Sprint(fieldByTag(reflect.ValueOf(item), tag).Interface())
+	}
+}
+
+// Returns the field of the struct with the given mapstructure tag
+func fieldByTag(v reflect.Value, tag string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("mapstructure") == tag {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// Reports whether a value is set on a level. Lists are only set when they
+// have items.
+func isSet(v reflect.Value) bool {
+	if v.Kind() == reflect.Slice {
+		return v.Len() > 0
+	}
+	return !v.IsZero()
+}
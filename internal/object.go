@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The output formats of the component variables. The flat format renders a
+// vercel_project_* variable per field, the object format renders a single
+// vercel_project_config variable.
+const (
+	outputFormatFlat   = "flat"
+	outputFormatObject = "object"
+)
+
+// Latest version of the vercel_project_config object. Increase it on every
+// change to the attributes so modules can adopt the changes deliberately, and
+// only render the new attributes for the new version.
+//
+//   - 1: the initial attributes
+//   - 2: adds vercel_json
+const objectFormatVersion = 2
+
+// ProjectConfigObject is the vercel_project_config object. The attribute
+// names match the ones of the vercel_project resource where possible.
+type ProjectConfigObject struct {
+	FormatVersion                 int                         `json:"format_version"`
+	TeamID                        *string                     `json:"team_id"`
+	Name                          *string                     `json:"name"`
+	Framework                     *string                     `json:"framework"`
+	BuildCommand                  *string                     `json:"build_command"`
+	IgnoreCommand                 *string                     `json:"ignore_command"`
+	RootDirectory                 *string                     `json:"root_directory"`
+	NodeVersion                   *string                     `json:"node_version"`
+	ServerlessFunctionRegion      *string                     `json:"serverless_function_region"`
+	ServerlessFunctionRegions     []string                    `json:"serverless_function_regions"`
+	ManualProductionDeployment    bool                        `json:"manual_production_deployment"`
+	ProtectionBypassForAutomation bool                        `json:"protection_bypass_for_automation"`
+	VercelAuthentication          objectVercelAuthentication  `json:"vercel_authentication"`
+	PasswordProtection            *objectPasswordProtection   `json:"password_protection"`
+	GitRepository                 *objectGitRepository        `json:"git_repository"`
+	EnvironmentVariablesMode      string                      `json:"environment_variables_mode"`
+	Environment                   []objectEnvironmentVariable `json:"environment"`
+	Domains                       []objectDomain              `json:"domains"`
+	RollingRelease                *objectRollingRelease       `json:"rolling_release"`
+	VercelJSON                    *string                     `json:"vercel_json" since:"2"`
+}
+
+// MarshalJSON leaves out the attributes which were added in a later version
+// than the format version of the object.
+func (o ProjectConfigObject) MarshalJSON() ([]byte, error) {
+	type plain ProjectConfigObject
+	body, err := json.Marshal(plain(o))
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	typ := reflect.TypeOf(o)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		since, ok := field.Tag.Lookup("since")
+		if !ok {
+			continue
+		}
+		version, err := strconv.Atoi(since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since tag on %s: %w", field.Name, err)
+		}
+		if version > o.FormatVersion {
+			delete(result, strings.Split(field.Tag.Get("json"), ",")[0])
+		}
+	}
+	return json.Marshal(result)
+}
+
+type objectVercelAuthentication struct {
+	DeploymentType string `json:"deployment_type"`
+}
+
+type objectPasswordProtection struct {
+	Password       string `json:"password"`
+	DeploymentType string `json:"deployment_type"`
+}
+
+type objectGitRepository struct {
+	Type             string  `json:"type"`
+	Repo             string  `json:"repo"`
+	ProductionBranch *string `json:"production_branch"`
+}
+
+type objectEnvironmentVariable struct {
+	Key    string   `json:"key"`
+	Value  string   `json:"value"`
+	Target []string `json:"target"`
+}
+
+type objectDomain struct {
+	Domain             string  `json:"domain"`
+	GitBranch          *string `json:"git_branch"`
+	Redirect           *string `json:"redirect"`
+	RedirectStatusCode *int64  `json:"redirect_status_code"`
+}
+
+type objectRollingRelease struct {
+	AdvancementType string                      `json:"advancement_type"`
+	Stages          []objectRollingReleaseStage `json:"stages"`
+}
+
+type objectRollingReleaseStage struct {
+	TargetPercentage int64  `json:"target_percentage"`
+	Duration         *int64 `json:"duration"`
+}
+
+// Returns the type of the vercel_project_config variable in the module
+// declaration for the given format version
+func projectObjectType(version int) string {
+	var sb strings.Builder
+	sb.WriteString(`object({
+    format_version                   = number
+    team_id                          = optional(string)
+    name                             = string
+    framework                        = optional(string)
+    build_command                    = optional(string)
+    ignore_command                   = optional(string)
+    root_directory                   = optional(string)
+    node_version                     = optional(string)
+    serverless_function_region       = optional(string)
+    serverless_function_regions      = list(string)
+    manual_production_deployment     = bool
+    protection_bypass_for_automation = bool
+    vercel_authentication            = object({ deployment_type = string })
+    password_protection              = optional(object({ password = string, deployment_type = string }))
+    git_repository                   = optional(object({ type = string, repo = string, production_branch = optional(string) }))
+    environment_variables_mode       = string
+    environment                      = list(object({ key = string, value = string, target = list(string) }))
+    domains                          = list(object({ domain = string, git_branch = optional(string), redirect = optional(string), redirect_status_code = optional(number) }))
+    rolling_release                  = optional(object({ advancement_type = string, stages = list(object({ target_percentage = number, duration = optional(number) })) }))
+`)
+	if version >= 2 {
+		sb.WriteString("    vercel_json                      = optional(string)\n")
+	}
+	sb.WriteString("  })")
+	return sb.String()
+}
+
+// Checks whether the plugin can render the object of the format version
+func checkFormatVersion(version int) error {
+	if version < 1 || version > objectFormatVersion {
+		return fmt.Errorf("unsupported format_version %d, expected 1 to %d", version, objectFormatVersion)
+	}
+	return nil
+}
+
+// ProjectObject returns the effective project configuration as the
+// vercel_project_config object.
+func (c *VercelConfig) ProjectObject() ProjectConfigObject {
+	p := c.ProjectConfig
+	result := ProjectConfigObject{
+		FormatVersion:             c.FormatVersion,
+		TeamID:                    optionalString(c.TeamID),
+		Name:                      optionalString(p.Name),
+		Framework:                 optionalString(p.Framework),
+		BuildCommand:              optionalString(p.BuildCommand),
+		IgnoreCommand:             optionalString(p.EffectiveIgnoreCommand()),
+		RootDirectory:             optionalString(p.RootDirectory),
+		NodeVersion:               optionalString(p.NodeVersion),
+		ServerlessFunctionRegion:  optionalString(p.ServerlessFunctionRegion),
+		ServerlessFunctionRegions: append([]string{}, p.ServerlessFunctionRegions...),
+		VercelAuthentication: objectVercelAuthentication{
+			DeploymentType: p.VercelAuthentication.DeploymentType,
+		},
+		EnvironmentVariablesMode: p.EnvironmentVariablesMode,
+		Environment:              []objectEnvironmentVariable{},
+		Domains:                  []objectDomain{},
+		VercelJSON:               optionalString(p.VercelJSON()),
+	}
+
+	if p.ManualProductionDeployment != nil {
+		result.ManualProductionDeployment = *p.ManualProductionDeployment
+	}
+
+	if p.ProtectionBypassForAutomation != nil {
+		result.ProtectionBypassForAutomation = *p.ProtectionBypassForAutomation
+	}
+
+	if p.PasswordProtection.Password != "" {
+		result.PasswordProtection = &objectPasswordProtection{
+			Password:       p.PasswordProtection.Password,
+			DeploymentType: p.PasswordProtection.DeploymentType,
+		}
+	}
+
+	if p.GitRepository.Repo != "" {
+		result.GitRepository = &objectGitRepository{
+			Type:             p.GitRepository.Type,
+			Repo:             p.GitRepository.Repo,
+			ProductionBranch: optionalString(p.GitRepository.ProductionBranch),
+		}
+	}
+
+	for _, env := range p.EnvironmentVariables {
+		result.Environment = append(result.Environment, objectEnvironmentVariable{
+			Key:    env.Key,
+			Value:  env.renderedValue(),
+			Target: env.Environment,
+		})
+	}
+
+	for _, domain := range p.ProjectDomains {
+		d := objectDomain{
+			Domain:    domain.Domain,
+			GitBranch: optionalString(domain.GitBranch),
+			Redirect:  optionalString(domain.Redirect),
+		}
+		if domain.RedirectStatusCode != 0 {
+			d.RedirectStatusCode = &domain.RedirectStatusCode
+		}
+		result.Domains = append(result.Domains, d)
+	}
+
+	if len(p.RollingRelease.Stages) > 0 {
+		rr := &objectRollingRelease{AdvancementType: p.RollingRelease.AdvancementType}
+		for _, stage := range p.RollingRelease.Stages {
+			s := objectRollingReleaseStage{TargetPercentage: stage.TargetPercentage}
+			if stage.Duration != 0 {
+				s.Duration = &stage.Duration
+			}
+			rr.Stages = append(rr.Stages, s)
+		}
+		result.RollingRelease = rr
+	}
+
+	return result
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -2,11 +2,11 @@ package internal
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/mach-composer/mach-composer-plugin-helpers/helpers"
 	"github.com/mach-composer/mach-composer-plugin-sdk/plugin"
 	"github.com/mach-composer/mach-composer-plugin-sdk/schema"
-	"github.com/mitchellh/mapstructure"
 )
 
 type VercelPlugin struct {
@@ -16,12 +16,22 @@ type VercelPlugin struct {
 	siteConfigs          map[string]*VercelConfig
 	siteComponentConfigs map[string]map[string]*VercelConfig
 	enabled              bool
+
+	// Directory of the mach-composer configuration, which relative paths in
+	// the configuration are resolved against. mach-composer does not pass it
+	// to the plugin, so it is read from configDirVariable. Empty when it is
+	// not known, in which case relative paths are rejected.
+	configDir string
 }
 
+// Environment variable with the directory of the mach-composer configuration
+const configDirVariable = "VERCEL_PLUGIN_CONFIG_DIR"
+
 func NewVercelPlugin() schema.MachComposerPlugin {
 	state := &VercelPlugin{
 		provider:    "1.12.0", // Provider version of `vercel/vercel`
 		siteConfigs: map[string]*VercelConfig{},
+		configDir:   os.Getenv(configDirVariable),
 	}
 	return plugin.NewPlugin(&schema.PluginSchema{
 		Identifier:          "vercel",
@@ -49,12 +59,11 @@ func (p *VercelPlugin) Configure(environment string, provider string) error {
 }
 
 func (p *VercelPlugin) SetGlobalConfig(data map[string]any) error {
-	cfg := NewVercelConfig()
-
-	if err := mapstructure.Decode(data, &cfg); err != nil {
+	cfg, err := decodeConfig(data, levelGlobal, describeLevel("", ""), p.configDir)
+	if err != nil {
 		return err
 	}
-	p.globalConfig = &cfg
+	p.globalConfig = cfg
 	p.enabled = true
 
 	return nil
@@ -66,19 +75,19 @@ func (p *VercelPlugin) GetValidationSchema() (*schema.ValidationSchema, error) {
 }
 
 func (p *VercelPlugin) SetSiteConfig(site string, data map[string]any) error {
-	cfg := NewVercelConfig()
-	if err := mapstructure.Decode(data, &cfg); err != nil {
+	cfg, err := decodeConfig(data, levelSite, describeLevel(site, ""), p.configDir)
+	if err != nil {
 		return err
 	}
-	p.siteConfigs[site] = &cfg
+	p.siteConfigs[site] = cfg
 	p.enabled = true
 	return nil
 }
 
 // Set config for a combination of site and component.
 func (p *VercelPlugin) SetSiteComponentConfig(site string, component string, data map[string]any) error {
-	cfg := NewVercelConfig()
-	if err := mapstructure.Decode(data, &cfg); err != nil {
+	cfg, err := decodeConfig(data, levelComponent, describeLevel(site, component), p.configDir)
+	if err != nil {
 		return err
 	}
 	if p.siteComponentConfigs == nil {
@@ -88,7 +97,7 @@ func (p *VercelPlugin) SetSiteComponentConfig(site string, component string, dat
 		p.siteComponentConfigs[site] = make(map[string]*VercelConfig)
 	}
 
-	p.siteComponentConfigs[site][component] = &cfg
+	p.siteComponentConfigs[site][component] = cfg
 	p.enabled = true
 	return nil
 }
@@ -98,8 +107,10 @@ func (p *VercelPlugin) RenderTerraformStateBackend(site string) (string, error)
 }
 
 func (p *VercelPlugin) RenderTerraformProviders(site string) (string, error) {
-	cfg := p.getConfig(site, "")
-
+	cfg, err := p.getConfig(site, "")
+	if err != nil {
+		return "", err
+	}
 	if cfg == nil {
 		return "", nil
 	}
@@ -114,128 +125,152 @@ func (p *VercelPlugin) RenderTerraformProviders(site string) (string, error) {
 	return result, nil
 }
 
-func (p *VercelPlugin) getComponentConfig(site string, component string) (*VercelConfig, error) {
-	cfg, ok := p.siteComponentConfigs[site][component]
-	if !ok {
-		return nil, fmt.Errorf("no config found for site %s and component %s", site, component)
+// A level of configuration, from global down to a component
+type configLevel struct {
+	name   string
+	config *VercelConfig
+}
+
+// Returns the configured levels for a site and component, ordered from global
+// to component with the presets they extend after the global level. The
+// overrides for the active environment are already applied on each level, and
+// the environment variable groups it uses are expanded.
+func (p *VercelPlugin) getLevels(site string, component string) []configLevel {
+	var levels []configLevel
+	if p.globalConfig != nil {
+		levels = append(levels, configLevel{name: "global", config: p.globalConfig})
+	}
+	if cfg, ok := p.siteConfigs[site]; ok {
+		levels = append(levels, configLevel{name: "site", config: cfg})
 	}
-	siteCfg, err := p.getSiteConfig(site)
-	if err == nil {
-		cfg = cfg.extendConfig(siteCfg)
+	if cfg, ok := p.siteComponentConfigs[site][component]; ok {
+		levels = append(levels, configLevel{name: "component", config: cfg})
 	}
 
-	return cfg, nil
-}
+	for i := range levels {
+		levels[i].config = levels[i].config.forEnvironment(p.environment)
+	}
 
-func (p *VercelPlugin) getSiteConfig(site string) (*VercelConfig, error) {
-	cfg, ok := p.siteConfigs[site]
-	if !ok {
-		return nil, fmt.Errorf("no config found for site %s", site)
+	// The presets are applied after the global level
+	if presets := p.presetLevels(levels); len(presets) > 0 {
+		global := 0
+		if p.globalConfig != nil {
+			global = 1
+		}
+		levels = append(levels[:global], append(presets, levels[global:]...)...)
 	}
-	cfg = cfg.extendConfig(p.globalConfig)
 
-	return cfg, nil
+	groups := p.environmentVariableGroups()
+	for i := range levels {
+		levels[i].config = levels[i].config.expandGroups(groups)
+	}
+	return levels
 }
 
-func (p *VercelPlugin) getConfig(site string, component string) *VercelConfig {
-	cfg, err := p.getComponentConfig(site, component)
-	if err != nil {
-		cfg, err = p.getSiteConfig(site)
-		if err != nil {
-			cfg = p.globalConfig
-		}
+// Returns the effective config of a site and component: the config of all
+// levels merged, with the defaults applied and the template expressions
+// evaluated.
+func (p *VercelPlugin) getConfig(site string, component string) (*VercelConfig, error) {
+	cfg := p.mergeConfig(site, component)
+	if cfg == nil {
+		return nil, nil
 	}
 
-	if cfg.ProjectConfig.VercelAuthentication.DeploymentType == "" {
-		cfg.ProjectConfig.VercelAuthentication.DeploymentType = "standard_protection"
-	}
+	cfg.applyDefaults()
 
-	if cfg.ProjectConfig.PasswordProtection.DeploymentType == "" {
-		cfg.ProjectConfig.PasswordProtection.DeploymentType = "standard_protection"
+	if err := evaluateTemplates(cfg, site, component, p.environment); err != nil {
+		return nil, err
 	}
+	return cfg, nil
+}
 
-	// Default behavior for Vercel is to output to all environments
-	// Set this as default field unless manually filled
-	for i := range cfg.ProjectConfig.EnvironmentVariables {
-		if len(cfg.ProjectConfig.EnvironmentVariables[i].Environment) == 0 {
-			cfg.ProjectConfig.EnvironmentVariables[i].Environment = []string{"development", "preview", "production"}
-		}
+// Returns the config of all levels merged, without the defaults applied
+func (p *VercelPlugin) mergeConfig(site string, component string) *VercelConfig {
+	levels := p.getLevels(site, component)
+	if len(levels) == 0 {
+		return nil
 	}
 
-	// keep existing behavior to false when omitted
-	if cfg.ProjectConfig.ManualProductionDeployment == nil {
-		defaultFalse := false
-		cfg.ProjectConfig.ManualProductionDeployment = &defaultFalse
+	cfg := levels[0].config.clone()
+	for _, level := range levels[1:] {
+		cfg = level.config.extendConfig(cfg)
 	}
 
 	return cfg
 }
 
 func (p *VercelPlugin) RenderTerraformResources(site string) (string, error) {
-	cfg := p.getConfig(site, "")
+	if err := p.checkSecretReferences(site, ""); err != nil {
+		return "", err
+	}
 
-	resourceTemplate := `
-		provider "vercel" {
-			{{ renderProperty "api_token" .APIToken }}
-			{{ renderProperty "team" .TeamID }}
-		}
-	`
+	providers, err := p.getProviders(site)
+	if err != nil {
+		return "", err
+	}
+	if providers == nil {
+		return "", nil
+	}
+
+	result, err := helpers.RenderGoTemplate(providersTemplate, providers)
+	if err != nil {
+		return "", err
+	}
 
-	return helpers.RenderGoTemplate(resourceTemplate, cfg)
+	variables, err := helpers.RenderGoTemplate(secretVariablesTemplate, p.secretVariables(site))
+	if err != nil {
+		return "", err
+	}
+	return result + variables, nil
 }
 
 func (p *VercelPlugin) RenderTerraformComponent(site string, component string) (*schema.ComponentSchema, error) {
-	cfg := p.getConfig(site, component)
+	cfg, err := p.getConfig(site, component)
+	if err != nil {
+		return nil, fmt.Errorf("component %s: %w", component, err)
+	}
 	if cfg == nil {
 		return nil, nil
 	}
 
-	template := `
-		{{ renderProperty "vercel_team_id" .TeamID }}
-		{{ renderProperty "vercel_project_name" .ProjectConfig.Name }}
-		{{ renderProperty "vercel_project_framework" .ProjectConfig.Framework }}
-		{{ renderProperty "vercel_project_build_command" .ProjectConfig.BuildCommand }}
-		{{ renderProperty "vercel_project_ignore_command" .ProjectConfig.IgnoreCommand }}
-		{{ renderProperty "vercel_project_root_directory" .ProjectConfig.RootDirectory }}
-		{{ if .ProjectConfig.NodeVersion }}{{ renderProperty "vercel_project_node_version" .ProjectConfig.NodeVersion }}{{ end }}
-		{{ renderProperty "vercel_project_serverless_function_region" .ProjectConfig.ServerlessFunctionRegion }}
-		{{ renderProperty "vercel_project_manual_production_deployment" .ProjectConfig.ManualProductionDeployment }}
-		{{ renderProperty "vercel_project_protection_bypass_for_automation" .ProjectConfig.ProtectionBypassForAutomation }}
-		vercel_project_vercel_authentication = {
-			{{ renderProperty "deployment_type" .ProjectConfig.VercelAuthentication.DeploymentType }}
-		}
-		vercel_project_password_protection = {
-			{{ renderProperty "password" .ProjectConfig.PasswordProtection.Password }}
-			{{ renderProperty "deployment_type" .ProjectConfig.PasswordProtection.DeploymentType }}
-		}
-		vercel_project_git_repository = {	
-			{{ renderProperty "production_branch" .ProjectConfig.GitRepository.ProductionBranch }}
-			{{ renderProperty "type" .ProjectConfig.GitRepository.Type }}
-			{{ renderProperty "repo" .ProjectConfig.GitRepository.Repo }}
+	if err := p.validateConfig(site, component); err != nil {
+		return nil, err
+	}
+
+	if err := p.checkSecretReferences(site, component); err != nil {
+		return nil, err
+	}
+
+	if err := checkProviderFeatures(p.provider, cfg); err != nil {
+		return nil, fmt.Errorf("component %s: %w", component, err)
+	}
+
+	if cfg.OutputFormat == outputFormatObject {
+		if err := checkFormatVersion(cfg.FormatVersion); err != nil {
+			return nil, fmt.Errorf("component %s: %w", component, err)
 		}
-		vercel_project_environment_variables = [{{range .ProjectConfig.EnvironmentVariables }}
-			{
-				{{ renderProperty "key" .Key }}
-				{{ renderProperty "value" .Value }}
-				{{ .DisplayEnvironments }}
-			},{{end}}
-		]
-		vercel_project_domains = [{{range .ProjectConfig.ProjectDomains }}
-			{
-				{{ renderProperty "domain" .Domain }}
-				{{ renderProperty "redirect_status_code" .RedirectStatusCode }}
-			},{{end}}
-		]
-	`
-
-	vars, err := helpers.RenderGoTemplate(template, cfg)
+	}
+
+	siteCfg, err := p.getConfig(site, "")
 	if err != nil {
 		return nil, err
 	}
+	alias := ""
+	if siteCfg != nil {
+		alias, err = providerAlias(siteCfg, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", component, err)
+		}
+	}
 
-	result := &schema.ComponentSchema{
-		Variables: vars,
+	result, err := renderComponent(component, cfg, alias)
+	if err != nil || result == nil {
+		return result, err
 	}
 
+	result.DependsOn = append(result.DependsOn, componentDependencies(component, cfg.projects())...)
+	if alias != "" {
+		result.Providers = append(result.Providers, fmt.Sprintf("vercel = vercel.%s", alias))
+	}
 	return result, nil
 }
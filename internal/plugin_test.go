@@ -2,6 +2,10 @@ package internal
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -419,6 +423,7 @@ func TestExtendEnvironmentVariables(t *testing.T) {
 		"project_config": map[string]any{
 			"manual_production_deployment": true,
 			"environment_variables":        siteVariables,
+			"custom_environments":          []any{"acceptance"},
 		},
 	}
 
@@ -833,3 +838,2018 @@ func TestNodeVersionInheritance(t *testing.T) {
 		assert.NotContains(t, component.Variables, "vercel_project_node_version")
 	})
 }
+
+func TestEnvironmentVariablesMode(t *testing.T) {
+	globalData := map[string]any{
+		"team_id": "test-team",
+		"project_config": map[string]any{
+			"environment_variables": []any{
+				map[string]any{"key": "GLOBAL_VARIABLE", "value": "global", "environment": []any{"production"}},
+			},
+		},
+	}
+
+	t.Run("defaults to inline", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetGlobalConfig(globalData)
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "test-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "key = \"GLOBAL_VARIABLE\"")
+		assert.Contains(t, component.Variables, "environment = [\"production\"]")
+		assert.NotContains(t, component.Variables, "vercel_project_bulk_environment_variables")
+	})
+
+	t.Run("bulk mode keeps inline list empty", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetGlobalConfig(globalData)
+		require.NoError(t, err)
+
+		siteData := map[string]any{
+			"project_config": map[string]any{
+				"environment_variables_mode": "bulk",
+				"environment_variables": []any{
+					map[string]any{"key": "GLOBAL_VARIABLE", "value": "site", "environment": []any{"preview"}},
+				},
+			},
+		}
+		err = plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "test-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_project_environment_variables = [\n\t\t]")
+		assert.Contains(t, component.Variables, "vercel_project_bulk_environment_variables = [")
+		assert.Contains(t, component.Variables, "{\n\t\t\t\tkey = \"GLOBAL_VARIABLE\"\n\t\t\t\tvalue = \"global\"\n\t\t\t\ttarget = [\"production\"]\n\n\t\t\t}")
+		assert.Contains(t, component.Variables, "{\n\t\t\t\tkey = \"GLOBAL_VARIABLE\"\n\t\t\t\tvalue = \"site\"\n\t\t\t\ttarget = [\"preview\"]\n\n\t\t\t}")
+		assert.NotContains(t, component.Variables, "environment = [")
+	})
+}
+
+func TestRollingRelease(t *testing.T) {
+	globalData := map[string]any{
+		"team_id": "test-team",
+		"project_config": map[string]any{
+			"rolling_release": map[string]any{
+				"stages": []any{
+					map[string]any{"target_percentage": 10, "duration": 15},
+					map[string]any{"target_percentage": 100},
+				},
+			},
+		},
+	}
+
+	t.Run("inherited from global defaults", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "3.3.0"))
+
+		err := plugin.SetGlobalConfig(globalData)
+		require.NoError(t, err)
+
+		err = plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"framework": "nextjs"},
+		})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "test-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_project_rolling_release = {")
+		assert.Contains(t, component.Variables, "advancement_type = \"automatic\"")
+		assert.Contains(t, component.Variables, "target_percentage = 10")
+		assert.Contains(t, component.Variables, "duration = 15")
+		assert.Contains(t, component.Variables, "target_percentage = 100")
+	})
+
+	t.Run("component overrides advancement type", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "3.3.0"))
+
+		err := plugin.SetGlobalConfig(globalData)
+		require.NoError(t, err)
+
+		err = plugin.SetSiteComponentConfig("my-site", "test-component", map[string]any{
+			"project_config": map[string]any{
+				"rolling_release": map[string]any{"advancement_type": "manual-approval"},
+			},
+		})
+		require.NoError(t, err)
+
+		err = plugin.SetSiteConfig("my-site", map[string]any{})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "test-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "advancement_type = \"manual-approval\"")
+		assert.Contains(t, component.Variables, "target_percentage = 10")
+	})
+
+	t.Run("not rendered when omitted", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "3.3.0"))
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{"team_id": "test-team"})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "test-component")
+		require.NoError(t, err)
+
+		assert.NotContains(t, component.Variables, "vercel_project_rolling_release")
+	})
+
+	t.Run("needs a newer provider than the default", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", ""))
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"rolling_release": map[string]any{
+					"stages": []any{map[string]any{"target_percentage": 100}},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = plugin.RenderTerraformComponent("my-site", "test-component")
+		assert.EqualError(t, err, `component test-component: vercel provider "~> 1.12.0" does not support `+
+			`project_config.rolling_release (requires 3.3.0), use at least version 3.3.0`)
+	})
+
+	t.Run("invalid stages are rejected when decoding", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "3.3.0"))
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"rolling_release": map[string]any{
+					"stages": []any{map[string]any{"target_percentage": 50}},
+				},
+			},
+		})
+		assert.ErrorContains(t, err, "project_config.rolling_release.stages: last target_percentage must be 100")
+
+		err = plugin.SetSiteComponentConfig("my-site", "test-component", map[string]any{
+			"environments": map[string]any{
+				"production": map[string]any{
+					"project_config": map[string]any{
+						"rolling_release": map[string]any{
+							"stages": []any{
+								map[string]any{"target_percentage": 50},
+								map[string]any{"target_percentage": 20},
+								map[string]any{"target_percentage": 100},
+							},
+						},
+					},
+				},
+			},
+		})
+		assert.ErrorContains(t, err, "environments.production.project_config.rolling_release.stages[1]: target_percentage 20 must be greater than 50")
+	})
+}
+
+func TestServerlessFunctionRegions(t *testing.T) {
+	t.Run("needs a newer provider than the default", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", ""))
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"serverless_function_regions": []any{"fra1", "iad1"}},
+		})
+		require.NoError(t, err)
+
+		_, err = plugin.RenderTerraformComponent("my-site", "test-component")
+		assert.ErrorContains(t, err, "project_config.serverless_function_regions (requires 2.10.0)")
+	})
+
+	t.Run("single region renders unchanged", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "3.3.0"))
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"serverless_function_region": "fra1"},
+		})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "test-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_project_serverless_function_region = \"fra1\"")
+		assert.NotContains(t, component.Variables, "vercel_project_serverless_function_regions")
+	})
+
+	t.Run("string is accepted as a list", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "3.3.0"))
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"serverless_function_regions": "fra1"},
+		})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "test-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_project_serverless_function_regions = [\"fra1\"]")
+	})
+
+	t.Run("other lists do not accept a string", func(t *testing.T) {
+		plugin := &VercelPlugin{siteConfigs: map[string]*VercelConfig{}}
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"custom_environments": "staging"},
+		})
+		assert.ErrorContains(t, err, "project_config.custom_environments")
+	})
+
+	t.Run("regions are merged across levels", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "3.3.0"))
+
+		err := plugin.SetGlobalConfig(map[string]any{
+			"project_config": map[string]any{"serverless_function_regions": []any{"fra1", "iad1"}},
+		})
+		require.NoError(t, err)
+
+		err = plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"serverless_function_regions": "hnd1"},
+		})
+		require.NoError(t, err)
+
+		err = plugin.SetSiteComponentConfig("my-site", "test-component", map[string]any{
+			"project_config": map[string]any{"serverless_function_regions": []any{"iad1", "sfo1"}},
+		})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "test-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_project_serverless_function_regions = [\"fra1\", \"iad1\", \"hnd1\", \"sfo1\"]")
+	})
+}
+
+func TestManagedMode(t *testing.T) {
+	siteData := map[string]any{
+		"team_id": "test-team",
+		"mode":    "managed",
+		"project_config": map[string]any{
+			"framework": "nextjs",
+			"git_repository": map[string]any{
+				"type": "github",
+				"repo": "mach-composer/my-project",
+			},
+			"environment_variables": []any{
+				map[string]any{"key": "API_URL", "value": "https://api.example.com", "environment": []any{"production"}},
+			},
+		},
+	}
+
+	componentData := map[string]any{
+		"project_config": map[string]any{
+			"name": "my-project",
+			"domains": []any{
+				map[string]any{"domain": "my-project.com", "redirect_status_code": 307},
+			},
+		},
+	}
+
+	t.Run("renders the project resources", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		err = plugin.SetSiteComponentConfig("my-site", "my-component", componentData)
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Resources, "resource \"vercel_project\" \"my_component\" {")
+		assert.Contains(t, component.Resources, "name = \"my-project\"")
+		assert.Contains(t, component.Resources, "team_id = \"test-team\"")
+		assert.Contains(t, component.Resources, "repo = \"mach-composer/my-project\"")
+		assert.Contains(t, component.Resources, "target = [\"production\"]")
+		assert.Contains(t, component.Resources, "prevent_destroy = true")
+		assert.NotContains(t, component.Resources, "password_protection")
+		assert.Contains(t, component.Resources, "resource \"vercel_project_domain\" \"my_component\" {")
+		assert.Contains(t, component.Resources, "project_id = vercel_project.my_component.id")
+		assert.Contains(t, component.Resources, "domain = each.key")
+		assert.Contains(t, component.Resources, "\"my-project.com\" = {")
+		assert.Contains(t, component.Resources, "redirect_status_code = 307")
+
+		assert.Contains(t, component.Variables, "vercel_project_id = vercel_project.my_component.id")
+		assert.Contains(t, component.Variables, "vercel_project_name = vercel_project.my_component.name")
+		assert.NotContains(t, component.Variables, "vercel_project_framework")
+	})
+
+	t.Run("domains are keyed by domain", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		err = plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"name": "my-project",
+				"domains": []any{
+					map[string]any{"domain": "my-project.com", "git_branch": "main"},
+					map[string]any{"domain": "my.project.com", "redirect": "my-project.com", "redirect_status_code": 308},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, strings.Count(component.Resources, "resource \"vercel_project_domain\""))
+		assert.Contains(t, component.Resources, "\"my-project.com\" = {")
+		assert.Contains(t, component.Resources, "\"my.project.com\" = {")
+		assert.Contains(t, component.Resources, "redirect             = null")
+		assert.Contains(t, component.Resources, "redirect             = \"my-project.com\"")
+	})
+
+	t.Run("bulk environment variables use a separate resource", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		err = plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"name":                       "my-project",
+				"environment_variables_mode": "bulk",
+			},
+		})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Resources, "resource \"vercel_project_environment_variables\" \"my_component\" {")
+		assert.Contains(t, component.Resources, "key = \"API_URL\"")
+		assert.NotContains(t, component.Resources, "environment = [")
+	})
+
+	t.Run("requires a project name", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		_, err = plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.ErrorContains(t, err, "project_config.name is required")
+	})
+
+	t.Run("renders multiple serverless function regions", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "2.10.0"))
+
+		err := plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		err = plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"name":                        "my-project",
+				"serverless_function_regions": []any{"fra1", "iad1"},
+			},
+		})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Resources, "resource_config = {")
+		assert.Contains(t, component.Resources, `function_default_regions = ["fra1", "iad1"]`)
+	})
+
+	t.Run("multiple serverless function regions need a newer provider", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		err = plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"name":                        "my-project",
+				"serverless_function_regions": []any{"fra1", "iad1"},
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.ErrorContains(t, err, "project_config.serverless_function_regions (requires 2.10.0)")
+	})
+}
+
+func TestObjectOutputFormat(t *testing.T) {
+	plugin := NewVercelPlugin()
+
+	err := plugin.SetGlobalConfig(map[string]any{
+		"team_id":       "test-team",
+		"output_format": "object",
+		"project_config": map[string]any{
+			"framework": "nextjs",
+			"environment_variables": []any{
+				map[string]any{"key": "SECRET", "value": "${data.sops_external.variables.data[\"secret\"]}", "environment": []any{"production"}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = plugin.SetSiteConfig("my-site", map[string]any{
+		"project_config": map[string]any{
+			"name": "my-project",
+			"domains": []any{
+				map[string]any{"domain": "my-project.com"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+	require.NoError(t, err)
+
+	assert.Contains(t, component.Variables, "vercel_project_config = {")
+	assert.Contains(t, component.Variables, "format_version                   = 1")
+	assert.Contains(t, component.Variables, "name                             = \"my-project\"")
+	assert.Contains(t, component.Variables, "team_id                          = \"test-team\"")
+	assert.Contains(t, component.Variables, "node_version                     = null")
+	assert.Contains(t, component.Variables, "git_repository                   = null")
+	assert.Contains(t, component.Variables, "value  = data.sops_external.variables.data[\"secret\"]")
+	assert.Contains(t, component.Variables, "redirect_status_code = null")
+	assert.NotContains(t, component.Variables, "vercel_project_name")
+	assert.NotContains(t, component.Variables, "vercel_json")
+}
+
+func TestObjectFormatVersion(t *testing.T) {
+	siteData := map[string]any{
+		"output_format": "object",
+		"project_config": map[string]any{
+			"name":  "my-project",
+			"crons": []any{map[string]any{"path": "/api/cron", "schedule": "0 5 * * *"}},
+		},
+	}
+
+	t.Run("version 2 renders vercel_json", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		err = plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{"format_version": 2})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "format_version                   = 2")
+		assert.Contains(t, component.Variables, "vercel_json = \"{")
+	})
+
+	t.Run("version 1 leaves out vercel_json", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "format_version                   = 1")
+		assert.NotContains(t, component.Variables, "vercel_json")
+	})
+
+	t.Run("unsupported versions are rejected", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", siteData)
+		require.NoError(t, err)
+
+		err = plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{"format_version": 3})
+		require.NoError(t, err)
+
+		_, err = plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, "component my-component: unsupported format_version 3, expected 1 to 2")
+	})
+}
+
+func TestEnvironmentOverrides(t *testing.T) {
+	globalData := map[string]any{
+		"team_id": "test-team",
+		"project_config": map[string]any{
+			"environment_variables": []any{
+				map[string]any{"key": "API_URL", "value": "https://api.example.com"},
+			},
+		},
+		"environments": map[string]any{
+			"test": map[string]any{
+				"team_id": "test-team-test",
+				"project_config": map[string]any{
+					"environment_variables": []any{
+						map[string]any{"key": "API_URL", "value": "https://api.test.example.com", "environment": []any{"production"}},
+					},
+				},
+			},
+		},
+	}
+
+	siteData := map[string]any{
+		"project_config": map[string]any{
+			"name": "my-project",
+		},
+		"environments": map[string]any{
+			"test": map[string]any{
+				"project_config": map[string]any{
+					"password_protection": map[string]any{
+						"password":        "test-password",
+						"deployment_type": "all_deployments",
+					},
+				},
+			},
+			"production": map[string]any{
+				"team_id": "test-team-production",
+			},
+		},
+	}
+
+	componentData := map[string]any{
+		"project_config": map[string]any{
+			"framework": "nextjs",
+		},
+		"environments": map[string]any{
+			"test": map[string]any{
+				"project_config": map[string]any{
+					"name": "my-project-test",
+				},
+			},
+		},
+	}
+
+	t.Run("applies the active environment on every level", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.Configure("test", "")
+		require.NoError(t, err)
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", componentData))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_team_id = \"test-team-test\"")
+		assert.Contains(t, component.Variables, "vercel_project_name = \"my-project-test\"")
+		assert.Contains(t, component.Variables, "vercel_project_framework = \"nextjs\"")
+		assert.Contains(t, component.Variables, "password = \"test-password\"")
+		assert.Contains(t, component.Variables, "deployment_type = \"all_deployments\"")
+		assert.Contains(t, component.Variables, "{\n\t\t\t\tkey = \"API_URL\"\n\t\t\t\tvalue = \"https://api.test.example.com\"\n\t\t\t\tenvironment = [\"production\"]\n\n\t\t\t}")
+		assert.Contains(t, component.Variables, "{\n\t\t\t\tkey = \"API_URL\"\n\t\t\t\tvalue = \"https://api.example.com\"\n\t\t\t\tenvironment = [\"development\", \"preview\"]\n\n\t\t\t}")
+	})
+
+	t.Run("a lower level wins over an environment override of a higher level", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.Configure("production", "")
+		require.NoError(t, err)
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"team_id": "component-team",
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_team_id = \"component-team\"")
+		assert.Contains(t, component.Variables, "vercel_project_name = \"my-project\"")
+		assert.Contains(t, component.Variables, "password = \"\"")
+	})
+
+	t.Run("ignores environments which are not active", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_team_id = \"test-team\"")
+		assert.Contains(t, component.Variables, "environment = [\"development\", \"preview\", \"production\"]")
+	})
+}
+
+func TestMultipleProjects(t *testing.T) {
+	siteData := map[string]any{
+		"team_id": "test-team",
+		"project_config": map[string]any{
+			"framework": "nextjs",
+			"environment_variables": []any{
+				map[string]any{"key": "API_URL", "value": "https://api.example.com"},
+			},
+		},
+	}
+
+	componentData := map[string]any{
+		"project_config": map[string]any{
+			"root_directory": "./apps",
+		},
+		"projects": map[string]any{
+			"storefront": map[string]any{
+				"name": "my-storefront",
+			},
+			"storybook": map[string]any{
+				"name":      "my-storybook",
+				"framework": "storybook",
+			},
+		},
+	}
+
+	t.Run("renders prefixed variables per project", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", componentData))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "storefront_vercel_project_name = \"my-storefront\"")
+		assert.Contains(t, component.Variables, "storefront_vercel_project_framework = \"nextjs\"")
+		assert.Contains(t, component.Variables, "storefront_vercel_project_root_directory = \"./apps\"")
+		assert.Contains(t, component.Variables, "storybook_vercel_project_name = \"my-storybook\"")
+		assert.Contains(t, component.Variables, "storybook_vercel_project_framework = \"storybook\"")
+		assert.Contains(t, component.Variables, "storybook_vercel_team_id = \"test-team\"")
+		assert.Contains(t, component.Variables, "storybook_vercel_project_environment_variables = [")
+		assert.NotContains(t, component.Variables, "\t\tvercel_project_name")
+	})
+
+	t.Run("renders a map of objects", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"output_format": "object",
+			"projects":      componentData["projects"],
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_projects = {")
+		assert.Contains(t, component.Variables, "storefront = {")
+		assert.Contains(t, component.Variables, "storybook = {")
+		assert.Contains(t, component.Variables, "\"my-storybook\"")
+		assert.NotContains(t, component.Variables, "vercel_project_config")
+	})
+
+	t.Run("renders resources per project in managed mode", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"mode":     "managed",
+			"projects": componentData["projects"],
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Resources, "resource \"vercel_project\" \"my_component_storefront\" {")
+		assert.Contains(t, component.Resources, "resource \"vercel_project\" \"my_component_storybook\" {")
+		assert.Contains(t, component.Variables, "storybook_vercel_project_id = vercel_project.my_component_storybook.id")
+	})
+
+	t.Run("single project renders unchanged", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "\t\tvercel_project_framework = \"nextjs\"")
+	})
+}
+
+func TestProviderAliases(t *testing.T) {
+	siteData := map[string]any{
+		"team_id":   "team-a",
+		"api_token": "token-a",
+	}
+
+	t.Run("renders an aliased provider per team", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "same-team", map[string]any{
+			"project_config": map[string]any{"name": "same-team"},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "other-team", map[string]any{
+			"team_id":        "team_b",
+			"api_token":      "token-b",
+			"project_config": map[string]any{"name": "other-team"},
+		}))
+
+		resources, err := plugin.RenderTerraformResources("my-site")
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(resources, "provider \"vercel\" {"))
+		assert.Contains(t, resources, "alias = \"team_b\"")
+		assert.Contains(t, resources, "api_token = \"token-b\"")
+		assert.Contains(t, resources, "team = \"team-a\"")
+
+		component, err := plugin.RenderTerraformComponent("my-site", "same-team")
+		require.NoError(t, err)
+		assert.Empty(t, component.Providers)
+
+		component, err = plugin.RenderTerraformComponent("my-site", "other-team")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"vercel = vercel.team_b"}, component.Providers)
+		assert.Contains(t, component.Variables, "vercel_team_id = \"team_b\"")
+	})
+
+	t.Run("uses the configured alias for managed resources", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"mode":           "managed",
+			"api_token":      "token-b",
+			"provider_alias": "agency",
+			"project_config": map[string]any{"name": "my-project"},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"vercel = vercel.agency"}, component.Providers)
+		assert.Contains(t, component.Resources, "provider = vercel.agency")
+	})
+
+	t.Run("requires an alias without a team_id", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{"api_token": "token-a"}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"api_token": "token-b",
+		}))
+
+		_, err := plugin.RenderTerraformResources("my-site")
+		assert.ErrorContains(t, err, "provider_alias is required")
+	})
+
+	t.Run("rejects conflicting credentials for an alias", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "first", map[string]any{
+			"team_id":   "team_b",
+			"api_token": "token-b",
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "second", map[string]any{
+			"team_id":   "team_b",
+			"api_token": "other-token",
+		}))
+
+		_, err := plugin.RenderTerraformResources("my-site")
+		assert.ErrorContains(t, err, "components first and second use provider alias team_b")
+	})
+}
+
+func TestSecretReferences(t *testing.T) {
+	t.Run("references are rendered as expressions", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"api_token": map[string]any{"env": "TF_VAR_vercel_api_token"},
+			"project_config": map[string]any{
+				"password_protection": map[string]any{
+					"password": map[string]any{"var": "vercel_password"},
+				},
+				"environment_variables": []any{
+					map[string]any{"key": "SECRET", "value": map[string]any{"sops": "secret"}},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		resources, err := plugin.RenderTerraformResources("my-site")
+		require.NoError(t, err)
+		assert.Contains(t, resources, "api_token = var.vercel_api_token")
+		assert.Contains(t, resources, "variable \"vercel_api_token\" {\n\t\t\ttype      = string\n\t\t\tsensitive = true\n\t\t}")
+		assert.NotContains(t, resources, "variable \"vercel_password\"")
+
+		component, err := plugin.RenderTerraformComponent("my-site", "test-component")
+		require.NoError(t, err)
+		assert.Contains(t, component.Variables, "password = var.vercel_password")
+		assert.Contains(t, component.Variables, "value = data.sops_external.variables.data[\"secret\"]")
+	})
+
+	t.Run("declares the variables of env references of the components", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{"api_token": "token"}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables": []any{
+					map[string]any{"key": "DATABASE_URL", "value": map[string]any{"env": "TF_VAR_database_url"}},
+				},
+			},
+		}))
+
+		resources, err := plugin.RenderTerraformResources("my-site")
+		require.NoError(t, err)
+		assert.Contains(t, resources, "variable \"database_url\" {")
+	})
+
+	t.Run("only env references declare variables", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"api_token": map[string]any{"var": "vercel_api_token"},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables": []any{
+					map[string]any{"key": "REGION", "value_from": map[string]any{"expression": "var.region"}},
+					map[string]any{"key": "DATABASE_URL", "value": map[string]any{"env": "TF_VAR_database_url"}},
+				},
+			},
+		}))
+
+		resources, err := plugin.RenderTerraformResources("my-site")
+		require.NoError(t, err)
+		assert.Contains(t, resources, "variable \"database_url\" {")
+		assert.NotContains(t, resources, "variable \"region\"")
+		assert.NotContains(t, resources, "variable \"vercel_api_token\"")
+	})
+
+	t.Run("only sensitive fields accept references", func(t *testing.T) {
+		plugin := &VercelPlugin{siteConfigs: map[string]*VercelConfig{}}
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"name": map[string]any{"sops": "name"},
+			},
+		})
+		assert.ErrorContains(t, err, "'project_config.name' expected type 'string', got unconvertible type 'map[string]interface {}'")
+	})
+
+	t.Run("env references must use TF_VAR_", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"api_token": map[string]any{"env": "VERCEL_API_TOKEN"},
+		})
+		assert.ErrorContains(t, err, "secret reference env VERCEL_API_TOKEN must be a TF_VAR_ environment variable")
+	})
+
+	t.Run("policy rejects literal secrets", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{
+			"require_secret_references": true,
+			"api_token":                 map[string]any{"var": "vercel_api_token"},
+		}))
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"environments": map[string]any{
+				"production": map[string]any{"api_token": "plaintext"},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables": []any{
+					map[string]any{"key": "API_URL", "value": "${data.sops_external.variables.data[\"api_url\"]}"},
+					map[string]any{"key": "SECRET", "value": "plaintext"},
+					map[string]any{"key": "TOKEN", "value": "${\"hunter2\"}"},
+					map[string]any{"key": "REGION", "value_from": map[string]any{"expression": "var.region"}},
+				},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, "require_secret_references: literal secret in "+
+			"sites[my-site].vercel.environments.production.api_token, "+
+			"sites[my-site].components[my-component].vercel.project_config.environment_variables[1].value, "+
+			"sites[my-site].components[my-component].vercel.project_config.environment_variables[2].value, "+
+			"sites[my-site].components[my-component].vercel.project_config.environment_variables[3].value, "+
+			"use a sops, var or env reference")
+
+		_, err = plugin.RenderTerraformResources("my-site")
+		assert.ErrorContains(t, err, "sites[my-site].vercel.environments.production.api_token")
+	})
+
+	t.Run("literal secrets are allowed without the policy", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{"api_token": "plaintext"}))
+
+		_, err := plugin.RenderTerraformResources("my-site")
+		assert.NoError(t, err)
+	})
+}
+
+func TestValidation(t *testing.T) {
+	t.Run("reports all violations with their levels", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{
+			"project_config": map[string]any{
+				"domains": []any{
+					map[string]any{"domain": "example.com"},
+				},
+				"password_protection": map[string]any{"deployment_type": "all_deployments"},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables": []any{
+					map[string]any{"key": "VERCEL_URL", "value": "https://example.com"},
+					map[string]any{"key": "API_URL", "value": "https://api.example.com", "environment": []any{"staging"}},
+				},
+				"custom_environments": []any{"qa"},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"domains": []any{
+					map[string]any{"domain": "example.com", "git_branch": "main"},
+					map[string]any{"domain": "www.example.com", "redirect": "example.org"},
+				},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, `invalid configuration for component my-component:
+  - project_config.domains: duplicate domain example.com (from global, component)
+  - project_config.domains[www.example.com].redirect: redirect target example.org is not a domain of the project (from component)
+  - project_config.environment_variables[API_URL].environment: unknown environment staging, expected one of development, preview, production, qa (from site)
+  - project_config.environment_variables[VERCEL_URL]: key is reserved by Vercel (from site)
+  - project_config.password_protection.deployment_type: deployment_type is set without a password (from global)`)
+	})
+
+	t.Run("environments are checked without custom environments", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables": []any{
+					map[string]any{"key": "API_URL", "value": "https://api.example.com", "environment": []any{"preview", "acceptance"}},
+				},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, `invalid configuration for component my-component:
+  - project_config.environment_variables[API_URL].environment: unknown environment acceptance, expected one of development, preview, production (from site)`)
+	})
+
+	t.Run("templates are evaluated before validating", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"domains": []any{
+					map[string]any{"domain": "my-component.example.com"},
+					map[string]any{"domain": "www.example.com", "redirect": "{{ site }}.example.com"},
+				},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"domains": []any{map[string]any{"domain": "{{ component }}.example.com", "git_branch": "main"}},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, `invalid configuration for component my-component:
+  - project_config.domains: duplicate domain my-component.example.com (from site, component)
+  - project_config.domains[www.example.com].redirect: redirect target my-site.example.com is not a domain of the project (from site)`)
+	})
+
+	t.Run("duplicate domains from appended levels are rejected", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"domains": []any{
+					map[string]any{"domain": "example.com"},
+					map[string]any{"domain": "www.example.com", "redirect": "example.com"},
+				},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"domains": []any{map[string]any{"domain": "example.com", "git_branch": "main"}},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, `invalid configuration for component my-component:
+  - project_config.domains: duplicate domain example.com (from site, component)`)
+	})
+
+	t.Run("identical domains from appended levels are merged", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"domains": []any{
+					map[string]any{"domain": "example.com"},
+					map[string]any{"domain": "www.example.com", "redirect": "example.com"},
+				},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"domains": []any{
+					map[string]any{"domain": "example.com"},
+					map[string]any{"domain": "shop.example.com"},
+				},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(component.Variables, `domain = "example.com"`))
+		assert.Contains(t, component.Variables, `domain = "shop.example.com"`)
+	})
+
+	t.Run("custom environments and additional projects", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"custom_environments": []any{"staging"},
+				"environment_variables": []any{
+					map[string]any{"key": "API_URL", "value": "https://api.example.com", "environment": []any{"staging"}},
+				},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"projects": map[string]any{
+				"storybook": map[string]any{
+					"name": "my-storybook",
+					"domains": []any{
+						map[string]any{"domain": "storybook.example.com", "redirect": "www.example.com"},
+					},
+				},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, `invalid configuration for component my-component:
+  - projects.storybook.domains[storybook.example.com].redirect: redirect target www.example.com is not a domain of the project (from component)`)
+	})
+}
+
+func TestMergeDirectivesAcrossLevels(t *testing.T) {
+	plugin := NewVercelPlugin()
+
+	require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+		"project_config": map[string]any{
+			"domains": []any{map[string]any{"domain": "example.com"}},
+		},
+	}))
+	require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+		"project_config": map[string]any{
+			"domains":       []any{map[string]any{"domain": "example.org"}},
+			"domains_merge": "replace",
+		},
+	}))
+
+	component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+	require.NoError(t, err)
+	assert.Contains(t, component.Variables, "domain = \"example.org\"")
+	assert.NotContains(t, component.Variables, "domain = \"example.com\"")
+}
+
+func TestUnsetInheritedValues(t *testing.T) {
+	globalData := map[string]any{
+		"project_config": map[string]any{
+			"build_command":                    "npm run build",
+			"protection_bypass_for_automation": true,
+			"git_repository": map[string]any{
+				"type":              "github",
+				"repo":              "mach-composer/my-project",
+				"production_branch": "main",
+			},
+			"password_protection": map[string]any{
+				"password":        "${var.password}",
+				"deployment_type": "all_deployments",
+			},
+		},
+	}
+
+	t.Run("component clears inherited values", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"build_command":                    nil,
+				"protection_bypass_for_automation": false,
+				"git_repository":                   map[string]any{"production_branch": "!unset"},
+				"password_protection":              "!unset",
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_project_build_command = \"\"")
+		assert.Contains(t, component.Variables, "vercel_project_protection_bypass_for_automation = false")
+		assert.Contains(t, component.Variables, "production_branch = \"\"")
+		assert.Contains(t, component.Variables, "repo = \"mach-composer/my-project\"")
+		assert.Contains(t, component.Variables, "password = \"\"")
+		assert.Contains(t, component.Variables, "deployment_type = \"standard_protection\"\n\t\t}\n\t\tvercel_project_git_repository")
+	})
+
+	t.Run("cleared values stay cleared with environment overrides", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("production", ""))
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"build_command": "!unset"},
+			"environments": map[string]any{
+				"production": map[string]any{
+					"project_config": map[string]any{"framework": "nextjs"},
+				},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_project_build_command = \"\"")
+		assert.Contains(t, component.Variables, "vercel_project_framework = \"nextjs\"")
+	})
+
+	t.Run("a lower level sets a cleared value again", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"build_command": nil},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{"build_command": "next build"},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "vercel_project_build_command = \"next build\"")
+	})
+}
+
+func TestUnknownFields(t *testing.T) {
+	// The schema accepts unknown fields, so they reach the plugin through the
+	// SDK and are reported with a suggestion.
+	t.Run("reports all unknown fields of a component", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"teamid": "team",
+			"project_config": map[string]any{
+				"domain": []any{map[string]any{"domain": "example.com"}},
+				"environment_variables": []any{
+					map[string]any{"key": "API_URL", "value": "https://api.example.com", "environments": []any{"preview"}},
+				},
+			},
+			"projects": map[string]any{
+				"storybook": map[string]any{"nme": "my-storybook"},
+			},
+			"environments": map[string]any{
+				"test": map[string]any{"project_config": map[string]any{"ignore_comand": "exit 0"}},
+			},
+		})
+		assert.EqualError(t, err, `unknown fields in component config of my-component in site my-site:
+  - environments[test].project_config.ignore_comand: unknown field, did you mean ignore_command?
+  - project_config.domain: unknown field, did you mean domains?
+  - project_config.environment_variables[0].environments: unknown field, did you mean environment?
+  - projects[storybook].nme: unknown field, did you mean name?
+  - teamid: unknown field, did you mean team_id?`)
+	})
+
+	t.Run("names the level", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetGlobalConfig(map[string]any{"project_config": map[string]any{"frmework": "nextjs"}})
+		assert.EqualError(t, err, "unknown fields in global config:\n"+
+			"  - project_config.frmework: unknown field, did you mean framework?")
+
+		err = plugin.SetSiteConfig("my-site", map[string]any{"project_config": map[string]any{"git_repository": map[string]any{"branch": "main"}}})
+		assert.EqualError(t, err, "unknown fields in site config of my-site:\n"+
+			"  - project_config.git_repository.branch: unknown field")
+	})
+
+	t.Run("reports fields of other levels", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetGlobalConfig(map[string]any{"projects": map[string]any{"storybook": map[string]any{"name": "storybook"}}})
+		assert.EqualError(t, err, "fields of other levels in global config:\n"+
+			"  - projects: only allowed on the component level")
+
+		err = plugin.SetSiteConfig("my-site", map[string]any{
+			"provider_alias": "agency",
+			"environments": map[string]any{
+				"test": map[string]any{"presets": map[string]any{"nextjs": map[string]any{"framework": "nextjs"}}},
+			},
+		})
+		assert.EqualError(t, err, "fields of other levels in site config of my-site:\n"+
+			"  - environments[test].presets: only allowed on the global level\n"+
+			"  - provider_alias: only allowed on the component level")
+
+		err = plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{"require_secret_references": false})
+		assert.EqualError(t, err, "fields of other levels in component config of my-component in site my-site:\n"+
+			"  - require_secret_references: only allowed on the global and site levels")
+	})
+}
+
+func TestTemplateExpressions(t *testing.T) {
+	globalData := map[string]any{
+		"team_id": "test-team",
+		"project_config": map[string]any{
+			"name": "{{ site }}-{{ component }}-{{ environment }}",
+			"domains": []any{
+				map[string]any{"domain": "{{ component }}.{{ site }}.example.com"},
+			},
+			"environment_variables": []any{
+				map[string]any{"key": "PROJECT_NAME", "value": `{{ config "project_config.name" }}`},
+				map[string]any{"key": "TEAM", "value": `{{ config "team_id" }}`},
+			},
+		},
+	}
+
+	t.Run("evaluates expressions after inheritance", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", ""))
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{"framework": "nextjs"},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, `vercel_project_name = "my-site-my-component-test"`)
+		assert.Contains(t, component.Variables, `domain = "my-component.my-site.example.com"`)
+		assert.Contains(t, component.Variables, `value = "my-site-my-component-test"`)
+		assert.Contains(t, component.Variables, `value = "test-team"`)
+	})
+
+	t.Run("evaluates the inherited value for every component", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "first", map[string]any{}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "second", map[string]any{}))
+
+		first, err := plugin.RenderTerraformComponent("my-site", "first")
+		require.NoError(t, err)
+		assert.Contains(t, first.Variables, `domain = "first.my-site.example.com"`)
+
+		second, err := plugin.RenderTerraformComponent("my-site", "second")
+		require.NoError(t, err)
+		assert.Contains(t, second.Variables, `domain = "second.my-site.example.com"`)
+	})
+
+	t.Run("only template fields are evaluated", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", ""))
+
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"name":           `{{ "{{" }} site }}`,
+				"ignore_command": "[ {{ site }} ]",
+				"environment_variables": []any{
+					map[string]any{"key": "IGNORE", "value": `{{ config "project_config.ignore_command" }}`},
+				},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Contains(t, component.Variables, `vercel_project_name = "{{ site }}"`)
+		assert.Contains(t, component.Variables, `vercel_project_ignore_command = "[ {{ site }} ]"`)
+		assert.Contains(t, component.Variables, `value = "[ {{ site }} ]"`)
+	})
+
+	t.Run("field referring to itself", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{
+			"project_config": map[string]any{
+				"name":           `{{ config "project_config.root_directory" }}`,
+				"root_directory": `{{ config "project_config.name" }}`,
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.ErrorContains(t, err, "component my-component: ")
+		assert.ErrorContains(t, err, "project_config.name refers to itself")
+	})
+
+	t.Run("unknown function", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{
+			"project_config": map[string]any{"name": "{{ sit }}"},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, `component my-component: template: project_config.name:1: function "sit" not defined`)
+	})
+}
+
+func TestEnvironmentVariableValueFrom(t *testing.T) {
+	componentData := map[string]any{
+		"project_config": map[string]any{
+			"environment_variables": []any{
+				map[string]any{"key": "API_URL", "value_from": map[string]any{"component": "api", "output": "url"}},
+				map[string]any{"key": "REGION", "value_from": map[string]any{"expression": "var.region"}, "environment": []any{"production"}},
+				map[string]any{"key": "SEARCH_URL", "value_from": map[string]any{"expression": `lookup(module.search.urls, "public")`}},
+				map[string]any{"key": "FLAGS", "value_from": map[string]any{"expression": "jsonencode({a = 1})"}},
+				map[string]any{"key": "MODE", "value_from": map[string]any{"expression": `var.debug ? "debug" : "}"`}},
+			},
+		},
+	}
+
+	t.Run("renders expressions unquoted and records dependencies", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{"team_id": "test-team"}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", componentData))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "value = module.api.url")
+		assert.Contains(t, component.Variables, "value = var.region")
+		assert.Contains(t, component.Variables, `value = lookup(module.search.urls, "public")`)
+		assert.Contains(t, component.Variables, "value = jsonencode({a = 1})")
+		assert.Contains(t, component.Variables, `value = var.debug ? "debug" : "}"`)
+		assert.Equal(t, []string{"module.api", "module.search"}, component.DependsOn)
+	})
+
+	t.Run("renders expressions in managed mode", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{"team_id": "test-team", "mode": "managed"}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"name":                  "my-project",
+				"environment_variables": componentData["project_config"].(map[string]any)["environment_variables"],
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Resources, "value = module.api.url")
+		assert.Contains(t, component.Resources, "value = jsonencode({a = 1})")
+		assert.Contains(t, component.Resources, `value = var.debug ? "debug" : "}"`)
+		assert.Equal(t, []string{"module.api", "module.search"}, component.DependsOn)
+	})
+
+	t.Run("renders expressions in the object output format", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{"team_id": "test-team", "output_format": "object"}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", componentData))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, "value  = module.api.url")
+		assert.Contains(t, component.Variables, "value  = jsonencode({a = 1})")
+		assert.Contains(t, component.Variables, `value  = var.debug ? "debug" : "}"`)
+	})
+
+	t.Run("renders other values quoted", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables": []any{
+					map[string]any{"key": "URLS", "value": "${module.api.url} ${module.search.url}"},
+				},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Contains(t, component.Variables, `value = "$${module.api.url} $${module.search.url}"`)
+		assert.Empty(t, component.DependsOn)
+	})
+
+	t.Run("value and value_from are exclusive", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables": []any{
+					map[string]any{"key": "API_URL", "value": "https://api.example.com", "value_from": map[string]any{"expression": "var.url"}},
+				},
+			},
+		})
+		assert.ErrorContains(t, err, "environment variable API_URL has both a value and value_from")
+	})
+
+	t.Run("component without output", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		err := plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables": []any{
+					map[string]any{"key": "API_URL", "value_from": map[string]any{"component": "api"}},
+				},
+			},
+		})
+		assert.ErrorContains(t, err, "value_from needs either an expression or a component and output")
+	})
+}
+
+func TestEnvironmentVariablesFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env.production"), []byte("API_URL=https://api.example.com\nDEBUG=false\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env.preview"), []byte("API_URL=https://api.preview.example.com\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env.invalid"), []byte("API_URL=https://api.example.com\nDEBUG\n"), 0o644))
+
+	t.Run("merges files with the inline variables", func(t *testing.T) {
+		plugin := &VercelPlugin{siteConfigs: map[string]*VercelConfig{}, configDir: dir}
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables": []any{
+					map[string]any{"key": "API_URL", "value": "https://api.site.example.com", "environment": []any{"development"}},
+				},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables_files": []any{
+					map[string]any{"path": ".env.production", "environment": []any{"production"}},
+					map[string]any{"path": filepath.Join(dir, ".env.preview"), "environment": []any{"preview"}},
+				},
+				"environment_variables": []any{
+					map[string]any{"key": "DEBUG", "value": "true", "environment": []any{"production"}},
+				},
+			},
+		}))
+
+		cfg, err := plugin.getConfig("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Equal(t, []ProjectEnvironmentVariable{
+			{Key: "API_URL", Value: "https://api.example.com", Environment: []string{"production"}, Source: ".env.production"},
+			{Key: "API_URL", Value: "https://api.preview.example.com", Environment: []string{"preview"}, Source: filepath.Join(dir, ".env.preview")},
+			{Key: "API_URL", Value: "https://api.site.example.com", Environment: []string{"development"}},
+			{Key: "DEBUG", Value: "true", Environment: []string{"production"}},
+		}, sortEnvironmentVariables(cfg.ProjectConfig.EnvironmentVariables))
+	})
+
+	t.Run("reports the file and line", func(t *testing.T) {
+		plugin := &VercelPlugin{siteConfigs: map[string]*VercelConfig{}, configDir: dir}
+
+		err := plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables_files": []any{map[string]any{"path": ".env.invalid"}},
+			},
+		})
+		assert.EqualError(t, err, fmt.Sprintf("component config of my-component in site my-site: %s:2: expected KEY=VALUE", filepath.Join(dir, ".env.invalid")))
+	})
+
+	t.Run("relative paths need the directory of the configuration", func(t *testing.T) {
+		plugin := &VercelPlugin{siteConfigs: map[string]*VercelConfig{}}
+
+		err := plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables_files": []any{map[string]any{"path": ".env.production"}},
+			},
+		})
+		assert.EqualError(t, err, "component config of my-component in site my-site: .env.production: relative paths need the directory of the mach-composer configuration, which mach-composer does not pass to the plugin: set VERCEL_PLUGIN_CONFIG_DIR when running mach-composer or use an absolute path")
+	})
+
+	t.Run("reads the directory of the configuration from the environment", func(t *testing.T) {
+		t.Setenv("VERCEL_PLUGIN_CONFIG_DIR", dir)
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables_files": []any{map[string]any{"path": ".env.production"}},
+			},
+		}))
+	})
+
+	t.Run("renders values literally", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".env.literal"), []byte("TOKEN=${var.token}\nNAME='{{ site }}'\nPRICE=$${amount}\n"), 0o644))
+		plugin := &VercelPlugin{provider: "1.12.0", siteConfigs: map[string]*VercelConfig{}, configDir: dir}
+
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables_files": []any{map[string]any{"path": ".env.literal"}},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Contains(t, component.Variables, `value = "$${var.token}"`)
+		assert.Contains(t, component.Variables, `value = "{{ site }}"`)
+		assert.Contains(t, component.Variables, `value = "$$${amount}"`)
+
+		cfg, err := plugin.getConfig("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Equal(t, []ProjectEnvironmentVariable{
+			{Key: "NAME", Value: "{{ site }}", Environment: knownEnvironments, Source: ".env.literal"},
+			{Key: "PRICE", Value: "$${amount}", Environment: knownEnvironments, Source: ".env.literal"},
+			{Key: "TOKEN", Value: "${var.token}", Environment: knownEnvironments, Source: ".env.literal"},
+		}, sortEnvironmentVariables(cfg.ProjectConfig.EnvironmentVariables))
+	})
+
+	t.Run("renders values literally in the object format", func(t *testing.T) {
+		plugin := &VercelPlugin{provider: "1.12.0", siteConfigs: map[string]*VercelConfig{}, configDir: dir}
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{"output_format": "object"}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables_files": []any{map[string]any{"path": ".env.literal"}},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Contains(t, component.Variables, `value  = "$${var.token}"`)
+		assert.Contains(t, component.Variables, `value  = "{{ site }}"`)
+		assert.Contains(t, component.Variables, `value  = "$$${amount}"`)
+	})
+
+	t.Run("require_secret_references names the file and key", func(t *testing.T) {
+		plugin := &VercelPlugin{siteConfigs: map[string]*VercelConfig{}, configDir: dir}
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{"require_secret_references": true}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"environment_variables_files": []any{map[string]any{"path": ".env.preview"}},
+				"environment_variables": []any{
+					map[string]any{"key": "SECRET", "value": map[string]any{"sops": "secret"}},
+					map[string]any{"key": "TOKEN", "value": "plaintext"},
+				},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.ErrorContains(t, err, "literal secret in "+
+			"sites[my-site].components[my-component].vercel.project_config.environment_variables[1].value, "+
+			"sites[my-site].components[my-component].vercel.project_config.environment_variables_files[.env.preview].API_URL,")
+	})
+}
+
+// Sorts environment variables by key and value for stable comparisons
+func sortEnvironmentVariables(variables []ProjectEnvironmentVariable) []ProjectEnvironmentVariable {
+	sort.Slice(variables, func(i, j int) bool {
+		if variables[i].Key != variables[j].Key {
+			return variables[i].Key < variables[j].Key
+		}
+		return variables[i].Value < variables[j].Value
+	})
+	return variables
+}
+
+func TestEnvironmentVariableGroups(t *testing.T) {
+	globalData := map[string]any{
+		"team_id": "test-team",
+		"environment_variable_groups": map[string]any{
+			"algolia": []any{
+				map[string]any{"key": "ALGOLIA_APP_ID", "value": "app"},
+				map[string]any{"key": "ALGOLIA_INDEX", "value": "products"},
+			},
+			"search": []any{
+				map[string]any{"key": "ALGOLIA_INDEX", "value": "search"},
+			},
+			"sentry": []any{
+				map[string]any{"key": "SENTRY_DSN", "value": "${var.sentry_dsn}"},
+			},
+		},
+		"environments": map[string]any{
+			"test": map[string]any{
+				"environment_variable_groups": map[string]any{
+					"sentry": []any{map[string]any{"key": "SENTRY_DSN", "value": "${var.sentry_test_dsn}"}},
+				},
+			},
+		},
+	}
+
+	t.Run("expands groups in order before merging", func(t *testing.T) {
+		plugin := &VercelPlugin{environment: "test", siteConfigs: map[string]*VercelConfig{}}
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"use_groups": []any{"sentry"}},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"use_groups": []any{"algolia", "search"},
+				"environment_variables": []any{
+					map[string]any{"key": "ALGOLIA_APP_ID", "value": "my-app", "environment": []any{"production"}},
+				},
+			},
+		}))
+
+		cfg, err := plugin.getConfig("my-site", "my-component")
+		require.NoError(t, err)
+		all := []string{"development", "preview", "production"}
+		assert.Equal(t, []ProjectEnvironmentVariable{
+			{Key: "ALGOLIA_APP_ID", Value: "app", Environment: []string{"development", "preview"}},
+			{Key: "ALGOLIA_APP_ID", Value: "my-app", Environment: []string{"production"}},
+			{Key: "ALGOLIA_INDEX", Value: "search", Environment: all},
+			{Key: "SENTRY_DSN", Value: "${var.sentry_test_dsn}", Environment: all},
+		}, sortEnvironmentVariables(cfg.ProjectConfig.EnvironmentVariables))
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"use_groups": []any{"commercetools"}},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{"use_groups": []any{"commercetools", "sentry"}},
+			"projects": map[string]any{
+				"storybook": map[string]any{"name": "storybook", "use_groups": []any{"algolia", "sentri"}},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, `invalid configuration for component my-component:
+  - project_config.use_groups: unknown environment variable group commercetools (from site, component)
+  - projects.storybook.use_groups: unknown environment variable group sentri (from component)`)
+	})
+}
+
+func TestPresets(t *testing.T) {
+	globalData := map[string]any{
+		"team_id": "test-team",
+		"project_config": map[string]any{
+			"framework":    "static",
+			"node_version": "18.x",
+		},
+		"presets": map[string]any{
+			"nextjs": map[string]any{
+				"framework":     "nextjs",
+				"build_command": "next build",
+			},
+			"nextjs-monorepo": map[string]any{
+				"extends":        []any{"nextjs"},
+				"root_directory": "apps/web",
+				"node_version":   "20.x",
+			},
+			"storybook": map[string]any{
+				"framework": "storybook",
+			},
+		},
+	}
+	siteData := map[string]any{
+		"project_config": map[string]any{"node_version": "22.x"},
+	}
+
+	t.Run("applies presets in order before the site and component", func(t *testing.T) {
+		plugin := &VercelPlugin{provider: "1.12.0", siteConfigs: map[string]*VercelConfig{}}
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"extends": []any{"nextjs-monorepo"},
+				"name":    "my-project",
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, `vercel_project_framework = "nextjs"`)
+		assert.Contains(t, component.Variables, `vercel_project_build_command = "next build"`)
+		assert.Contains(t, component.Variables, `vercel_project_root_directory = "apps/web"`)
+		assert.Contains(t, component.Variables, `vercel_project_node_version = "22.x"`)
+
+		entries, err := plugin.explain("my-site", "my-component")
+		require.NoError(t, err)
+		levels := map[string]string{}
+		for _, entry := range entries {
+			levels[entry.Path] = entry.Level
+		}
+		assert.Equal(t, "preset nextjs", levels["project_config.build_command"])
+		assert.Equal(t, "preset nextjs-monorepo", levels["project_config.root_directory"])
+		assert.Equal(t, "site", levels["project_config.node_version"])
+	})
+
+	t.Run("applies presets to projects", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(globalData))
+		require.NoError(t, plugin.SetSiteConfig("my-site", siteData))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{"extends": []any{"nextjs-monorepo"}},
+			"projects": map[string]any{
+				"storefront": map[string]any{"name": "my-storefront"},
+				"storybook":  map[string]any{"name": "my-storybook", "extends": []any{"storybook"}},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		assert.Contains(t, component.Variables, `storefront_vercel_project_framework = "nextjs"`)
+		assert.Contains(t, component.Variables, `storybook_vercel_project_framework = "storybook"`)
+		assert.Contains(t, component.Variables, `storybook_vercel_project_root_directory = "apps/web"`)
+	})
+
+	t.Run("reports unknown presets and cycles", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{
+			"presets": map[string]any{
+				"a": map[string]any{"extends": []any{"b"}},
+				"b": map[string]any{"extends": []any{"a"}},
+				"c": map[string]any{"extends": []any{"remix"}},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{"extends": []any{"a", "nextjs"}},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		assert.EqualError(t, err, `invalid configuration for component my-component:
+  - project_config.extends: unknown preset nextjs (from component)
+  - presets.a.extends: cycle a -> b -> a (from global)
+  - presets.b.extends: cycle b -> a -> b (from global)
+  - presets.c.extends: unknown preset remix (from global)`)
+	})
+}
+
+func TestVercelJSON(t *testing.T) {
+	t.Run("merges the routes and crons across levels", func(t *testing.T) {
+		plugin := &VercelPlugin{environment: "test", provider: "1.12.0", siteConfigs: map[string]*VercelConfig{}}
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{
+			"project_config": map[string]any{
+				"routing": map[string]any{
+					"headers": []any{
+						map[string]any{
+							"source":  "/(.*)",
+							"headers": []any{map[string]any{"key": "X-Frame-Options", "value": "DENY"}},
+						},
+					},
+					"redirects": []any{
+						map[string]any{"source": "/old", "destination": "/new", "permanent": true},
+					},
+				},
+				"crons": []any{
+					map[string]any{"path": "/api/cleanup", "schedule": "0 3 * * *"},
+				},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"name": "my-project",
+				"routing": map[string]any{
+					"redirects": []any{
+						map[string]any{"source": "/old", "destination": "/newer", "status_code": 307},
+					},
+					"rewrites": []any{
+						map[string]any{"source": "/blog/:path*", "destination": "https://blog.example.com/:path*"},
+					},
+				},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"crons": []any{
+					map[string]any{"path": "/api/cleanup", "schedule": "0 4 * * *"},
+					map[string]any{"path": "/api/report", "schedule": "*/15 8-18 * * 1-5"},
+				},
+				"functions": map[string]any{
+					"api/*.ts": map[string]any{"memory": 1024, "max_duration": 60},
+				},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		expected := `{
+  "$schema": "https://openapi.vercel.sh/vercel.json",
+  "headers": [
+    {
+      "source": "/(.*)",
+      "headers": [
+        {
+          "key": "X-Frame-Options",
+          "value": "DENY"
+        }
+      ]
+    }
+  ],
+  "redirects": [
+    {
+      "source": "/old",
+      "destination": "/newer",
+      "statusCode": 307
+    }
+  ],
+  "rewrites": [
+    {
+      "source": "/blog/:path*",
+      "destination": "https://blog.example.com/:path*"
+    }
+  ],
+  "crons": [
+    {
+      "path": "/api/cleanup",
+      "schedule": "0 4 * * *"
+    },
+    {
+      "path": "/api/report",
+      "schedule": "*/15 8-18 * * 1-5"
+    }
+  ],
+  "functions": {
+    "api/*.ts": {
+      "memory": 1024,
+      "maxDuration": 60
+    }
+  }
+}
+`
+		cfg, err := plugin.getConfig("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Equal(t, expected, cfg.ProjectConfig.VercelJSON())
+		assert.Contains(t, component.Variables, "vercel_project_vercel_json = ")
+		assert.Contains(t, component.Variables, `\"schedule\": \"*/15 8-18 * * 1-5\"`)
+	})
+
+	t.Run("does not render the variable without settings", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "1.12.0"))
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"name": "my-project"},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.NotContains(t, component.Variables, "vercel_project_vercel_json")
+	})
+
+	t.Run("validates the limits of vercel", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "1.12.0"))
+
+		crons := []any{}
+		for i := 0; i < 41; i++ {
+			crons = append(crons, map[string]any{"path": fmt.Sprintf("/api/cron-%d", i), "schedule": "0 0 * * *"})
+		}
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"name":  "my-project",
+				"crons": crons,
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"routing": map[string]any{
+					"redirects": []any{
+						map[string]any{"source": "old", "destination": "/new", "permanent": true, "status_code": 308},
+					},
+				},
+				"crons": []any{
+					map[string]any{"path": "/api/cron-0", "schedule": "0 0 * JAN MON"},
+				},
+				"functions": map[string]any{
+					"api/*.ts": map[string]any{"memory": 64},
+				},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.Error(t, err)
+		assert.Equal(t, `invalid configuration for component my-component:
+  - project_config.routing.redirects[old].source: source must start with / (from component)
+  - project_config.routing.redirects[old]: permanent and status_code cannot both be set (from component)
+  - project_config.crons: 41 crons exceed the limit of 40 (from site, component)
+  - project_config.crons[/api/cron-0].schedule: schedule "0 0 * JAN MON": month has an invalid value "JAN" (from site, component)
+  - project_config.functions[api/*.ts].memory: memory must be between 128 and 3009 (from component)`, err.Error())
+	})
+}
+
+func TestIgnoreCommand(t *testing.T) {
+	t.Run("generates the command from the merged settings", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "1.12.0"))
+
+		require.NoError(t, plugin.SetGlobalConfig(map[string]any{
+			"project_config": map[string]any{
+				"ignore": map[string]any{
+					"paths":         []any{"packages/config"},
+					"skip_branches": []any{"renovate/*"},
+				},
+			},
+		}))
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{"name": "my-project"},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"ignore": map[string]any{
+					"paths":           []any{"apps/{{ component }}", "docs/it's here"},
+					"turbo_workspace": "{{ component }}",
+				},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Contains(t, component.Variables, `vercel_project_ignore_command = "[ \"$VERCEL_GIT_COMMIT_REF\" = 'renovate/*' ] || (npx turbo-ignore 'my-component' && git diff HEAD^ HEAD --quiet -- 'packages/config' 'apps/my-component' 'docs/it'\\''s here')"`)
+	})
+
+	t.Run("keeps a configured ignore_command", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "1.12.0"))
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"name":           "my-project",
+				"ignore_command": "exit 1",
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Contains(t, component.Variables, `vercel_project_ignore_command = "exit 1"`)
+	})
+
+	t.Run("rejects both ignore and ignore_command", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "1.12.0"))
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"project_config": map[string]any{
+				"name":           "my-project",
+				"ignore_command": "exit 1",
+			},
+		}))
+		require.NoError(t, plugin.SetSiteComponentConfig("my-site", "my-component", map[string]any{
+			"project_config": map[string]any{
+				"ignore": map[string]any{"turbo_workspace": "web"},
+			},
+		}))
+
+		_, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.Error(t, err)
+		assert.Equal(t, "invalid configuration for component my-component:\n  - project_config.ignore: ignore and ignore_command cannot both be set (from site, component)", err.Error())
+	})
+
+	t.Run("renders the command in managed mode", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "1.12.0"))
+
+		require.NoError(t, plugin.SetSiteConfig("my-site", map[string]any{
+			"mode": "managed",
+			"project_config": map[string]any{
+				"name":   "my-project",
+				"ignore": map[string]any{"skip_branches": []any{"main", "develop"}},
+			},
+		}))
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+		assert.Contains(t, component.Resources, `ignore_command = "[ \"$VERCEL_GIT_COMMIT_REF\" = 'main' ] || [ \"$VERCEL_GIT_COMMIT_REF\" = 'develop' ]"`)
+	})
+}
+
+func TestIgnoreConfigCommand(t *testing.T) {
+	assert.Equal(t, "", (&IgnoreConfig{}).command())
+	assert.Equal(t, "npx turbo-ignore 'web'", (&IgnoreConfig{TurboWorkspace: "web"}).command())
+	assert.Equal(t, "git diff HEAD^ HEAD --quiet -- 'apps/web'", (&IgnoreConfig{Paths: []string{"apps/web"}}).command())
+	assert.Equal(t,
+		`[ "$VERCEL_GIT_COMMIT_REF" = 'main' ] || npx turbo-ignore 'web'`,
+		(&IgnoreConfig{TurboWorkspace: "web", SkipBranches: []string{"main"}}).command())
+	assert.Equal(t,
+		"npx turbo-ignore 'web' && git diff HEAD^ HEAD --quiet -- '$(rm -rf /)'",
+		(&IgnoreConfig{TurboWorkspace: "web", Paths: []string{"$(rm -rf /)"}}).command())
+}
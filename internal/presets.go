@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Prefix of the name of the configuration level of a preset
+const presetLevelPrefix = "preset "
+
+// Returns the presets of the global config, with the overrides of the active
+// environment applied
+func (p *VercelPlugin) presets() map[string]ProjectConfig {
+	if p.globalConfig == nil {
+		return nil
+	}
+	return p.globalConfig.forEnvironment(p.environment).Presets
+}
+
+// Returns the presets in the order in which they are applied when extending
+// the given presets: each preset after the presets it extends itself, and
+// every preset once. Unknown presets and cycles are skipped, presetErrors
+// reports them.
+func resolvePresets(presets map[string]ProjectConfig, names []string) []string {
+	var result []string
+	var visit func(name string, chain []string)
+	visit = func(name string, chain []string) {
+		preset, ok := presets[name]
+		if !ok || slices.Contains(chain, name) || slices.Contains(result, name) {
+			return
+		}
+		for _, parent := range preset.Extends {
+			visit(parent, append(chain, name))
+		}
+		result = append(result, name)
+	}
+	for _, name := range names {
+		visit(name, nil)
+	}
+	return result
+}
+
+// Returns a level for every preset extended by the project configs of the
+// levels, in the order in which they are applied. A preset level holds the
+// preset as the project_config, or as a project when a project extends it.
+func (p *VercelPlugin) presetLevels(levels []configLevel) []configLevel {
+	presets := p.presets()
+	if len(presets) == 0 {
+		return nil
+	}
+
+	var order []string
+	configs := map[string]*VercelConfig{}
+	add := func(names []string, project string) {
+		for _, name := range resolvePresets(presets, names) {
+			cfg, ok := configs[name]
+			if !ok {
+				cfg = &VercelConfig{}
+				configs[name] = cfg
+				order = append(order, name)
+			}
+			if project == "" {
+				cfg.ProjectConfig = presets[name]
+				continue
+			}
+			if cfg.Projects == nil {
+				cfg.Projects = map[string]ProjectConfig{}
+			}
+			cfg.Projects[project] = presets[name]
+		}
+	}
+
+	var extends []string
+	projects := map[string][]string{}
+	for _, level := range levels {
+		extends = append(extends, level.config.ProjectConfig.Extends...)
+		for _, name := range sortedKeys(level.config.Projects) {
+			projects[name] = append(projects[name], level.config.Projects[name].Extends...)
+		}
+	}
+	add(extends, "")
+	for _, name := range sortedKeys(projects) {
+		add(projects[name], name)
+	}
+
+	result := make([]configLevel, 0, len(order))
+	for _, name := range order {
+		result = append(result, configLevel{name: presetLevelPrefix + name, config: configs[name]})
+	}
+	return result
+}
+
+// Returns a line for every unknown preset extended on a level or by another
+// preset, and for every cycle of presets extending each other
+func (p *VercelPlugin) presetErrors(site string, component string) []string {
+	presets := p.presets()
+
+	var lines []string
+	origins := map[string][]string{}
+	unknown := func(level string, path string, config ProjectConfig) {
+		for _, name := range config.Extends {
+			if _, ok := presets[name]; ok {
+				continue
+			}
+			key := fmt.Sprintf("%s.extends: unknown preset %s", path, name)
+			if _, ok := origins[key]; !ok {
+				lines = append(lines, key)
+			}
+			if !slices.Contains(origins[key], level) {
+				origins[key] = append(origins[key], level)
+			}
+		}
+	}
+
+	for _, level := range p.getLevels(site, component) {
+		if strings.HasPrefix(level.name, presetLevelPrefix) {
+			continue
+		}
+		unknown(level.name, "project_config", level.config.ProjectConfig)
+		for _, name := range sortedKeys(level.config.Projects) {
+			unknown(level.name, "projects."+name, level.config.Projects[name])
+		}
+	}
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("  - %s (from %s)", line, strings.Join(origins[line], ", "))
+	}
+
+	for _, name := range sortedKeys(presets) {
+		for _, parent := range presets[name].Extends {
+			if _, ok := presets[parent]; !ok {
+				lines = append(lines, fmt.Sprintf("  - presets.%s.extends: unknown preset %s (from global)", name, parent))
+			}
+		}
+		if cycle := presetCycle(presets, name, nil); cycle != nil {
+			lines = append(lines, fmt.Sprintf("  - presets.%s.extends: cycle %s (from global)", name, strings.Join(cycle, " -> ")))
+		}
+	}
+	return lines
+}
+
+// Returns the chain of presets leading from a preset back to itself, or nil
+// when the preset is not part of a cycle
+func presetCycle(presets map[string]ProjectConfig, name string, chain []string) []string {
+	if len(chain) > 0 && chain[0] == name {
+		return append(chain, name)
+	}
+	if slices.Contains(chain, name) {
+		return nil
+	}
+	for _, parent := range presets[name].Extends {
+		if cycle := presetCycle(presets, parent, append(slices.Clone(chain), name)); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
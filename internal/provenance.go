@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The origin of an effective configuration value
+type provenance struct {
+	Path  string
+	Value string
+	// Level which set the value, the defaults are reported as `default`
+	Level string
+	// Values of the parent levels which this value overrode, nearest first
+	Overridden []overriddenValue
+	// Whether Level cleared the value, the cleared value is the first of
+	// Overridden
+	Unset bool
+	// The configured value when Value is the result of its template
+	// expressions
+	Template string
+}
+
+type overriddenValue struct {
+	Level string
+	Value string
+}
+
+// Returns the origin of every effective field of a component, by replaying
+// the merge of the levels done by getConfig and recording which level changed
+// or repeated each value. Values are reported with their template expressions
+// evaluated.
+func (p *VercelPlugin) explain(site string, component string) ([]provenance, error) {
+	levels := p.getLevels(site, component)
+	if len(levels) == 0 {
+		return nil, nil
+	}
+
+	origins := map[string]*provenance{}
+	// Records the values of the merged config which the level changed or set
+	// again. A value which comes from an environment override of the level is
+	// reported as such.
+	record := func(level string, merged map[string]string, own map[string]string, raw map[string]string) {
+		for path, value := range merged {
+			current, ok := origins[path]
+			if ok && !current.Unset && current.Value == value && own[path] != value {
+				continue
+			}
+
+			entry := &provenance{Path: path, Value: value, Level: level}
+			if own[path] != raw[path] {
+				entry.Level += ", environments." + p.environment
+			}
+			if ok {
+				entry.Overridden = current.history()
+			}
+			origins[path] = entry
+		}
+		// Values which the level cleared are kept, so the value they had
+		// before is still reported
+		for path, current := range origins {
+			if _, ok := merged[path]; !ok && !current.Unset {
+				origins[path] = &provenance{Path: path, Level: level, Unset: true, Overridden: current.history()}
+			}
+		}
+	}
+
+	var cfg *VercelConfig
+	for i, level := range levels {
+		if i == 0 {
+			cfg = level.config.clone()
+		} else {
+			cfg = level.config.extendConfig(cfg)
+		}
+
+		own := flattenConfig(level.config)
+		raw := own
+		if rawLevel := p.rawLevel(level.name, site, component); rawLevel != nil {
+			raw = flattenConfig(rawLevel.expandGroups(p.environmentVariableGroups()))
+		}
+		record(level.name, flattenConfig(cfg), own, raw)
+	}
+
+	cfg = cfg.clone()
+	cfg.applyDefaults()
+	record("default", flattenConfig(cfg), nil, nil)
+
+	evaluated := cfg.clone()
+	if err := evaluateTemplates(evaluated, site, component, p.environment); err != nil {
+		return nil, err
+	}
+	for path, value := range evaluatedValues(cfg, evaluated) {
+		if entry, ok := origins[path]; ok && !entry.Unset && entry.Value != value {
+			entry.Template = entry.Value
+			entry.Value = value
+		}
+	}
+
+	result := make([]provenance, 0, len(origins))
+	for _, entry := range origins {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
+
+// Returns the values of a config with evaluated templates by the paths of the
+// config before the evaluation. Domains are flattened by their name, which may
+// itself be a template.
+func evaluatedValues(cfg *VercelConfig, evaluated *VercelConfig) map[string]string {
+	paths := map[string]string{}
+	addDomains := func(prefix string, configured []ProjectDomain, result []ProjectDomain) {
+		for i, domain := range configured {
+			paths[fmt.Sprintf("%s.domains[%s]", prefix, result[i].Domain)] = fmt.Sprintf("%s.domains[%s]", prefix, domain.Domain)
+		}
+	}
+	addDomains("project_config", cfg.ProjectConfig.ProjectDomains, evaluated.ProjectConfig.ProjectDomains)
+	for name, project := range cfg.Projects {
+		addDomains("projects."+name, project.ProjectDomains, evaluated.Projects[name].ProjectDomains)
+	}
+
+	result := map[string]string{}
+	for path, value := range flattenConfig(evaluated) {
+		if prefix, _, ok := strings.Cut(path, "]"); ok {
+			if configured, ok := paths[prefix+"]"]; ok {
+				path = configured + strings.TrimPrefix(path, prefix+"]")
+			}
+		}
+		result[path] = value
+	}
+	return result
+}
+
+// Returns the values this entry replaces when a later level changes it,
+// nearest first. A cleared value is reported as `unset`.
+func (e *provenance) history() []overriddenValue {
+	value := e.Value
+	if e.Unset {
+		value = "unset"
+	}
+	return append([]overriddenValue{{Level: e.Level, Value: value}}, e.Overridden...)
+}
+
+// Returns the configuration of a level without the environment overrides, or
+// nil for the levels of presets
+func (p *VercelPlugin) rawLevel(level string, site string, component string) *VercelConfig {
+	switch level {
+	case "global":
+		return p.globalConfig
+	case "site":
+		return p.siteConfigs[site]
+	case "component":
+		return p.siteComponentConfigs[site][component]
+	}
+	return nil
+}
+
+// Returns the configured values of the config by their path
+func flattenConfig(cfg *VercelConfig) map[string]string {
+	result := map[string]string{}
+	flattenValue("", reflect.ValueOf(cfg), false, result)
+	return result
+}
+
+func flattenValue(path string, v reflect.Value, nullable bool, result map[string]string) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			flattenValue(path, v.Elem(), true, result)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			tag := v.Type().Field(i).Tag.Get("mapstructure")
+			if tag == "" || tag == "-" || tag == "environments" || v.Field(i).Type() == reflect.TypeOf(unsetFields{}) {
+				continue
+			}
+			flattenValue(joinPath(path, tag), v.Field(i), false, result)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		for _, key := range keys {
+			flattenValue(joinPath(path, key.String()), v.MapIndex(key), false, result)
+		}
+	case reflect.Slice:
+		flattenSlice(path, v, result)
+	default:
+		if nullable || !v.IsZero() {
+			result[path] = formatValue(v.Interface())
+		}
+	}
+}
+
+// Flattens the items of a list by their key where they have one, so the same
+// item can be followed across the levels
+func flattenSlice(path string, v reflect.Value, result map[string]string) {
+	switch items := v.Interface().(type) {
+	case []ProjectEnvironmentVariable:
+		for _, env := range items {
+			environments := env.Environment
+			if len(environments) == 0 {
+				environments = knownEnvironments
+			}
+			for _, environment := range environments {
+				result[fmt.Sprintf("%s[%s].%s", path, env.Key, environment)] = formatValue(env.Value)
+			}
+		}
+	case []ProjectDomain:
+		for _, domain := range items {
+			flattenValue(fmt.Sprintf("%s[%s]", path, domain.Domain), reflect.ValueOf(domain), false, result)
+		}
+	case []string:
+		if len(items) > 0 {
+			result[path] = formatValue(items)
+		}
+	default:
+		for i := 0; i < v.Len(); i++ {
+			flattenValue(fmt.Sprintf("%s[%d]", path, i), v.Index(i), false, result)
+		}
+	}
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []string:
+		quoted := make([]string, len(v))
+		for i, item := range v {
+			quoted[i] = fmt.Sprintf("%q", item)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mach-composer/mach-composer-plugin-helpers/helpers"
+)
+
+// A vercel provider block of a site
+type providerConfig struct {
+	// Alias of the provider, empty for the default provider of the site
+	Alias    string
+	TeamID   string
+	APIToken string
+}
+
+const providersTemplate = `{{ range . }}
+		provider "vercel" {
+			{{ if .Alias }}{{ renderProperty "alias" .Alias }}{{ end }}
+			{{ renderProperty "api_token" .APIToken }}
+			{{ renderProperty "team" .TeamID }}
+		}
+	{{ end }}`
+
+// Returns the alias of the provider a component uses, or an empty string when
+// the component uses the same team and api token as its site.
+func providerAlias(siteCfg *VercelConfig, cfg *VercelConfig) (string, error) {
+	if cfg.TeamID == siteCfg.TeamID && cfg.APIToken == siteCfg.APIToken {
+		return "", nil
+	}
+	if cfg.ProviderAlias != "" {
+		return cfg.ProviderAlias, nil
+	}
+	if cfg.TeamID == "" {
+		return "", fmt.Errorf("provider_alias is required when the api_token differs from the site without a team_id")
+	}
+	return helpers.Slugify(cfg.TeamID), nil
+}
+
+// Returns the default provider of the site followed by the aliased providers
+// of its components, sorted by alias.
+func (p *VercelPlugin) getProviders(site string) ([]providerConfig, error) {
+	siteCfg, err := p.getConfig(site, "")
+	if err != nil || siteCfg == nil {
+		return nil, err
+	}
+
+	components := make([]string, 0, len(p.siteComponentConfigs[site]))
+	for component := range p.siteComponentConfigs[site] {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	aliases := map[string]providerConfig{}
+	users := map[string]string{}
+	for _, component := range components {
+		cfg, err := p.getConfig(site, component)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", component, err)
+		}
+		alias, err := providerAlias(siteCfg, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", component, err)
+		}
+		if alias == "" {
+			continue
+		}
+
+		provider := providerConfig{Alias: alias, TeamID: cfg.TeamID, APIToken: cfg.APIToken}
+		if existing, ok := aliases[alias]; ok && existing != provider {
+			return nil, fmt.Errorf(
+				"components %s and %s use provider alias %s with a different team_id or api_token",
+				users[alias], component, alias)
+		}
+		if _, ok := aliases[alias]; !ok {
+			aliases[alias] = provider
+			users[alias] = component
+		}
+	}
+
+	aliased := make([]providerConfig, 0, len(aliases))
+	for _, provider := range aliases {
+		aliased = append(aliased, provider)
+	}
+	sort.Slice(aliased, func(i, j int) bool { return aliased[i].Alias < aliased[j].Alias })
+
+	return append([]providerConfig{{TeamID: siteCfg.TeamID, APIToken: siteCfg.APIToken}}, aliased...), nil
+}
@@ -1,20 +1,42 @@
 package internal
 
 import (
+	"bytes"
 	"embed"
 	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/mach-composer/mach-composer-plugin-sdk/schema"
+	"golang.org/x/exp/slices"
 )
 
+//go:generate go test -run TestSchemas -update
+
 //go:embed schemas/*
 var schemas embed.FS
 
+// The configuration levels, each validated with its own schema
+const (
+	levelGlobal    = "global"
+	levelSite      = "site"
+	levelComponent = "component"
+)
+
+// The schema files of the levels, generated from the VercelConfig struct
+var schemaFiles = map[string]string{
+	levelGlobal:    "schemas/global-config.json",
+	levelSite:      "schemas/site-config.json",
+	levelComponent: "schemas/component-config.json",
+}
+
 func getSchema() *schema.ValidationSchema {
 	s := schema.ValidationSchema{}
-	loadSchemaNode("schemas/global-config.json", &s.GlobalConfigSchema)
-	loadSchemaNode("schemas/site-config.json", &s.SiteConfigSchema)
-	loadSchemaNode("schemas/component-config.json", &s.SiteComponentConfigSchema)
+	loadSchemaNode(schemaFiles[levelGlobal], &s.GlobalConfigSchema)
+	loadSchemaNode(schemaFiles[levelSite], &s.SiteConfigSchema)
+	loadSchemaNode(schemaFiles[levelComponent], &s.SiteComponentConfigSchema)
 
 	return &s
 }
@@ -24,7 +46,219 @@ func loadSchemaNode(filename string, dst any) {
 	if err != nil {
 		panic(err)
 	}
-	if err := json.Unmarshal(body, dst); err != nil {
+	// The validator only accepts numbers of keywords such as minimum as
+	// json.Number
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(dst); err != nil {
 		panic(err)
 	}
 }
+
+// A node of a JSON schema. The fields are in the order in which they are
+// written to the schema files.
+type jsonSchema struct {
+	Type                 string                 `json:"type,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Minimum              *int64                 `json:"minimum,omitempty"`
+	Maximum              *int64                 `json:"maximum,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	Definitions          map[string]*jsonSchema `json:"definitions,omitempty"`
+}
+
+// Types which are rendered once in the definitions of a schema and referenced
+// by name
+var schemaDefinitions = map[reflect.Type]string{
+	reflect.TypeOf(ProjectConfig{}): "project_config",
+}
+
+// Generates the schema of a configuration level from the VercelConfig struct.
+// Besides the mapstructure name and type of a field, the schema uses the
+// `description` tag and the options in the `schema` tag:
+//
+//   - secret: the value is a literal or a secret reference
+//   - enum=a|b: the allowed values
+//   - levels=a|b: the levels on which the field may be set, all by default
+//   - required: the field must be set
+//   - minimum=n, maximum=n: the bounds of an integer
+//   - single: a list of strings which may also be given as a single string
+//
+// Merge directives refer to the allowed directives, and the fields of structs
+// with an unset field may also be cleared with null or !unset. Unknown fields
+// and fields of other levels are accepted, the plugin reports them with a
+// suggestion when decoding.
+func generateSchema(level string) *jsonSchema {
+	root := structSchema(reflect.TypeOf(VercelConfig{}), level)
+	root.Description = fmt.Sprintf("%s%s Vercel configuration", strings.ToUpper(level[:1]), level[1:])
+	root.Definitions = map[string]*jsonSchema{
+		"unset": {
+			Description: "Clears the value inherited from the parent level",
+			Enum:        []any{nil, unsetValue},
+		},
+		"merge": {
+			Description: "How the list of this level is merged into the list of the parent level",
+			Enum:        []any{mergeReplace, mergeAppend, mergeByKey},
+		},
+		"secret": {
+			Description: "A literal, a ${...} expression or a reference to a sops key, terraform variable or TF_VAR_ environment variable",
+			OneOf: []*jsonSchema{
+				{Type: "string"},
+				secretReferenceSchema("sops"),
+				secretReferenceSchema("var"),
+				secretReferenceSchema("env"),
+			},
+		},
+	}
+	for typ, name := range schemaDefinitions {
+		root.Definitions[name] = structSchema(typ, level)
+	}
+	return root
+}
+
+// Returns the schema file of a level
+func renderSchema(level string) ([]byte, error) {
+	body, err := json.MarshalIndent(generateSchema(level), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(body, '\n'), nil
+}
+
+func secretReferenceSchema(kind string) *jsonSchema {
+	return &jsonSchema{
+		Type:                 "object",
+		Required:             []string{kind},
+		Properties:           map[string]*jsonSchema{kind: {Type: "string"}},
+		AdditionalProperties: false,
+	}
+}
+
+func structSchema(typ reflect.Type, level string) *jsonSchema {
+	result := &jsonSchema{
+		Type:       "object",
+		Properties: map[string]*jsonSchema{},
+	}
+	_, clearable := typ.FieldByName("Unset")
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Type == reflect.TypeOf(unsetFields{}) || field.Tag.Get("mapstructure") == "-" {
+			continue
+		}
+
+		options := schemaOptions(field)
+		if levels, ok := options["levels"]; ok && !slices.Contains(strings.Split(levels, "|"), level) {
+			continue
+		}
+
+		name := field.Tag.Get("mapstructure")
+		if _, ok := options["required"]; ok {
+			result.Required = append(result.Required, name)
+		}
+
+		property := fieldSchema(field, options, level)
+		if clearable && field.Type.Kind() != reflect.Map {
+			property = &jsonSchema{AnyOf: []*jsonSchema{property, {Ref: "#/definitions/unset"}}}
+		}
+		result.Properties[name] = property
+	}
+	return result
+}
+
+func fieldSchema(field reflect.StructField, options map[string]string, level string) *jsonSchema {
+	_, secret := options["secret"]
+	_, single := options["single"]
+
+	var result *jsonSchema
+	switch {
+	case secret:
+		result = &jsonSchema{Ref: "#/definitions/secret"}
+	case field.Tag.Get("merge") == mergeRuleDirective:
+		result = &jsonSchema{Ref: "#/definitions/merge"}
+	case single:
+		result = &jsonSchema{OneOf: []*jsonSchema{{Type: "string"}, typeSchema(field.Type, level)}}
+	default:
+		result = typeSchema(field.Type, level)
+	}
+
+	if values, ok := options["enum"]; ok {
+		for _, value := range strings.Split(values, "|") {
+			if result.Type == "integer" {
+				number, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					panic(fmt.Sprintf("invalid enum value %q of field %s", value, field.Name))
+				}
+				result.Enum = append(result.Enum, number)
+				continue
+			}
+			result.Enum = append(result.Enum, value)
+		}
+	}
+
+	result.Minimum = integerOption(field, options, "minimum")
+	result.Maximum = integerOption(field, options, "maximum")
+
+	result.Description = field.Tag.Get("description")
+	return result
+}
+
+// Returns the integer value of a schema option, nil when it is not set
+func integerOption(field reflect.StructField, options map[string]string, name string) *int64 {
+	value, ok := options[name]
+	if !ok {
+		return nil
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("invalid %s %q of field %s", name, value, field.Name))
+	}
+	return &number
+}
+
+func typeSchema(typ reflect.Type, level string) *jsonSchema {
+	if name, ok := schemaDefinitions[typ]; ok {
+		return &jsonSchema{Ref: "#/definitions/" + name}
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return typeSchema(typ.Elem(), level)
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Slice:
+		return &jsonSchema{Type: "array", Items: typeSchema(typ.Elem(), level)}
+	case reflect.Map:
+		if typ.Elem() == reflect.TypeOf(VercelConfig{}) {
+			return &jsonSchema{Type: "object", AdditionalProperties: &jsonSchema{Ref: "#"}}
+		}
+		return &jsonSchema{Type: "object", AdditionalProperties: typeSchema(typ.Elem(), level)}
+	case reflect.Struct:
+		return structSchema(typ, level)
+	}
+	panic(fmt.Sprintf("no schema for type %s", typ))
+}
+
+// Parses the comma separated options of the schema tag of a field
+func schemaOptions(field reflect.StructField) map[string]string {
+	result := map[string]string{}
+	tag := field.Tag.Get("schema")
+	if tag == "" {
+		return result
+	}
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		result[key] = value
+	}
+	return result
+}
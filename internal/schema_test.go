@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the generated schema files")
+
+func TestSchemas(t *testing.T) {
+	for level, filename := range schemaFiles {
+		t.Run(level, func(t *testing.T) {
+			expected, err := renderSchema(level)
+			require.NoError(t, err)
+
+			if *update {
+				require.NoError(t, os.WriteFile(filepath.FromSlash(filename), expected, 0o644))
+				return
+			}
+
+			body, err := schemas.ReadFile(filename)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(body),
+				"%s is stale, regenerate it with go generate ./internal", filename)
+		})
+	}
+}
+
+func TestSchemaLevels(t *testing.T) {
+	s := getSchema()
+
+	tests := []struct {
+		name      string
+		schema    map[string]any
+		data      map[string]any
+		wantValid bool
+	}{
+		{
+			name:      "ignore_command",
+			schema:    s.SiteComponentConfigSchema,
+			data:      map[string]any{"project_config": map[string]any{"ignore_command": "exit 0"}},
+			wantValid: true,
+		},
+		// Unknown fields and fields of other levels are reported by the
+		// plugin when decoding
+		{
+			name:      "unknown field",
+			schema:    s.GlobalConfigSchema,
+			data:      map[string]any{"project_config": map[string]any{"ignore_comand": "exit 0"}},
+			wantValid: true,
+		},
+		{
+			name:      "unknown field in environment override",
+			schema:    s.SiteConfigSchema,
+			data:      map[string]any{"environments": map[string]any{"test": map[string]any{"teamid": "team"}}},
+			wantValid: true,
+		},
+		{
+			name:      "component with its own api_token and provider_alias",
+			schema:    s.SiteComponentConfigSchema,
+			data:      map[string]any{"api_token": map[string]any{"var": "token"}, "provider_alias": "agency"},
+			wantValid: true,
+		},
+		{
+			name:      "single serverless_function_regions",
+			schema:    s.SiteConfigSchema,
+			data:      map[string]any{"project_config": map[string]any{"serverless_function_regions": "fra1"}},
+			wantValid: true,
+		},
+		{
+			name:   "single custom_environments",
+			schema: s.SiteConfigSchema,
+			data:   map[string]any{"project_config": map[string]any{"custom_environments": "staging"}},
+		},
+		{
+			name:   "rolling release target_percentage above 100",
+			schema: s.SiteConfigSchema,
+			data: map[string]any{"project_config": map[string]any{"rolling_release": map[string]any{
+				// The validator only checks the bounds of numbers decoded from
+				// JSON, which are json.Number
+				"stages": []any{map[string]any{"target_percentage": json.Number("150")}},
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Validate(tt.schema, tt.data)
+			if tt.wantValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"golang.org/x/exp/slices"
+)
+
+// The kinds of secret references
+const (
+	referenceSops = "sops"
+	referenceVar  = "var"
+	referenceEnv  = "env"
+)
+
+// A reference to a secret, given in the configuration instead of a literal
+// value. Exactly one of the fields is set.
+type secretReference struct {
+	// Key in the sops encrypted mach-composer variables file
+	Sops string `mapstructure:"sops"`
+	// Name of a mach-composer variable
+	Var string `mapstructure:"var"`
+	// Name of a TF_VAR_ environment variable
+	Env string `mapstructure:"env"`
+}
+
+// Returns the kind and the raw terraform expression of the secret reference.
+// Keys of the sops encrypted variables file are looked up through its
+// sops_external data source, variables are rendered as mach-composer's
+// ${var...} reference. Environment variables are read by terraform through
+// the variable which the plugin declares for them.
+func (r secretReference) expression() (string, string, error) {
+	switch {
+	case r.Sops != "" && r.Var == "" && r.Env == "":
+		return referenceSops, variablesFileLookup(r.Sops), nil
+	case r.Var != "" && r.Sops == "" && r.Env == "":
+		return referenceVar, fmt.Sprintf("${var.%s}", r.Var), nil
+	case r.Env != "" && r.Sops == "" && r.Var == "":
+		// Terraform only reads variables from the environment through the
+		// TF_VAR_ prefix
+		name, ok := strings.CutPrefix(r.Env, "TF_VAR_")
+		if !ok || !terraformIdentifier.MatchString(name) {
+			return "", "", fmt.Errorf("secret reference env %s must be a TF_VAR_ environment variable", r.Env)
+		}
+		return referenceEnv, fmt.Sprintf("${var.%s}", name), nil
+	}
+	return "", "", fmt.Errorf("a secret reference needs exactly one of sops, var or env")
+}
+
+func variablesFileLookup(key string) string {
+	return fmt.Sprintf(`${data.sops_external.variables.data[%q]}`, key)
+}
+
+var terraformIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// Returns a decode hook which decodes the secret references given for the
+// sensitive fields of a struct, the fields with the secret option in their
+// schema tag, into their expressions. The kind of every reference is recorded
+// in the references by its expression. Other fields do not accept references.
+func secretReferenceHook(references map[string]string) mapstructure.DecodeHookFuncType {
+	return func(from reflect.Type, to reflect.Type, data any) (any, error) {
+		if from.Kind() != reflect.Map || to.Kind() != reflect.Struct {
+			return data, nil
+		}
+		values, ok := data.(map[string]any)
+		if !ok {
+			return data, nil
+		}
+
+		var result map[string]any
+		for i := 0; i < to.NumField(); i++ {
+			field := to.Field(i)
+			if _, secret := schemaOptions(field)["secret"]; !secret {
+				continue
+			}
+			name := field.Tag.Get("mapstructure")
+			value, ok := values[name].(map[string]any)
+			if !ok {
+				continue
+			}
+
+			var ref secretReference
+			if err := mapstructure.Decode(value, &ref); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			kind, expression, err := ref.expression()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			// A var and an env reference can render the same expression,
+			// the env reference needs the variable declaration
+			if references[expression] != referenceEnv {
+				references[expression] = kind
+			}
+			if result == nil {
+				result = make(map[string]any, len(values))
+				for key, v := range values {
+					result[key] = v
+				}
+			}
+			result[name] = expression
+		}
+		if result == nil {
+			return data, nil
+		}
+		return result, nil
+	}
+}
+
+// Matches the lookup mach-composer renders for a ${var...} reference to the
+// sops encrypted variables file
+var variablesFileReference = regexp.MustCompile(`^\$\{data\.sops_external\.variables\.data\["[^"]*"\]\}$`)
+
+// Reports whether the value is empty or the expression of a secret reference
+// on the level of the configuration
+func (c *VercelConfig) isSecretReference(value string) bool {
+	if value == "" {
+		return true
+	}
+	if _, ok := c.SecretReferences[value]; ok {
+		return true
+	}
+	return variablesFileReference.MatchString(value)
+}
+
+// A value of a sensitive field, with the YAML path of the field. Values read
+// from a dotenv file are literal, whatever they contain.
+type sensitiveValue struct {
+	path    string
+	value   string
+	literal bool
+}
+
+// Returns the values of the sensitive fields in the configuration of a single
+// level, including its environment overrides.
+func sensitiveValues(cfg *VercelConfig, path string) []sensitiveValue {
+	result := []sensitiveValue{{path: path + ".api_token", value: cfg.APIToken}}
+	result = append(result, cfg.ProjectConfig.sensitiveValues(path+".project_config")...)
+
+	for _, name := range sortedKeys(cfg.EnvironmentVariableGroups) {
+		for i, env := range cfg.EnvironmentVariableGroups[name] {
+			result = append(result, sensitiveValue{path: fmt.Sprintf("%s.environment_variable_groups.%s[%d].value", path, name, i), value: env.Value})
+		}
+	}
+	for _, name := range sortedKeys(cfg.Presets) {
+		preset := cfg.Presets[name]
+		result = append(result, preset.sensitiveValues(path+".presets."+name)...)
+	}
+	for _, name := range sortedKeys(cfg.Projects) {
+		project := cfg.Projects[name]
+		result = append(result, project.sensitiveValues(path+".projects."+name)...)
+	}
+	for _, name := range sortedKeys(cfg.Environments) {
+		override := cfg.Environments[name]
+		result = append(result, sensitiveValues(&override, path+".environments."+name)...)
+	}
+	return result
+}
+
+func (c *ProjectConfig) sensitiveValues(path string) []sensitiveValue {
+	result := []sensitiveValue{{path: path + ".password_protection.password", value: c.PasswordProtection.Password}}
+	for i, env := range c.EnvironmentVariables {
+		if env.Source != "" {
+			result = append(result, sensitiveValue{fmt.Sprintf("%s.environment_variables_files[%s].%s", path, env.Source, env.Key), env.Value, true})
+			continue
+		}
+		result = append(result, sensitiveValue{path: fmt.Sprintf("%s.environment_variables[%d].value", path, i), value: env.Value})
+	}
+	return result
+}
+
+// Returns the YAML paths of the sensitive fields holding a literal value in
+// the configuration of a single level, including its environment overrides.
+func plaintextSecrets(cfg *VercelConfig, path string) []string {
+	var result []string
+	for _, v := range sensitiveValues(cfg, path) {
+		if v.literal || !cfg.isSecretReference(v.value) {
+			result = append(result, v.path)
+		}
+	}
+	return result
+}
+
+// Returns the names of the terraform variables of the env references of a
+// site and its components, sorted
+func (p *VercelPlugin) secretVariables(site string) []string {
+	configs := []*VercelConfig{p.globalConfig, p.siteConfigs[site]}
+	for _, component := range sortedKeys(p.siteComponentConfigs[site]) {
+		configs = append(configs, p.siteComponentConfigs[site][component])
+	}
+
+	var result []string
+	for _, cfg := range configs {
+		if cfg == nil {
+			continue
+		}
+		for expression, kind := range cfg.SecretReferences {
+			name := strings.TrimSuffix(strings.TrimPrefix(expression, "${var."), "}")
+			if kind == referenceEnv && !slices.Contains(result, name) {
+				result = append(result, name)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Declares the terraform variables of the env references in the root module
+// of a site, so terraform reads them from their TF_VAR_ environment variable
+const secretVariablesTemplate = `{{ range . }}
+		variable "{{ . }}" {
+			type      = string
+			sensitive = true
+		}
+	{{ end }}`
+
+// Returns an error naming every literal secret on the levels of the site and
+// component when the require_secret_references policy is enabled.
+func (p *VercelPlugin) checkSecretReferences(site string, component string) error {
+	cfg := p.mergeConfig(site, component)
+	if cfg == nil || cfg.RequireSecretReferences == nil || !*cfg.RequireSecretReferences {
+		return nil
+	}
+
+	var paths []string
+	if p.globalConfig != nil {
+		paths = append(paths, plaintextSecrets(p.globalConfig, "global.vercel")...)
+	}
+	if siteCfg, ok := p.siteConfigs[site]; ok {
+		paths = append(paths, plaintextSecrets(siteCfg, fmt.Sprintf("sites[%s].vercel", site))...)
+	}
+	if componentCfg, ok := p.siteComponentConfigs[site][component]; ok {
+		paths = append(paths, plaintextSecrets(componentCfg, fmt.Sprintf("sites[%s].components[%s].vercel", site, component))...)
+	}
+
+	if len(paths) > 0 {
+		return fmt.Errorf(
+			"require_secret_references: literal secret in %s, use a sops, var or env reference",
+			strings.Join(paths, ", "))
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Evaluates the template expressions of a config
+type templateEvaluator struct {
+	cfg   *VercelConfig
+	funcs template.FuncMap
+
+	// The evaluated values by path, and the paths being evaluated to detect
+	// fields which refer to themselves
+	values     map[string]string
+	evaluating map[string]bool
+}
+
+// Evaluates the Go template expressions in the fields of an effective config
+// tagged with `template:"true"`, such as `{{ site }}-{{ component }}`. Besides
+// the site, component and mach-composer environment, an expression can use the
+// effective value of another field with `{{ config "project_config.framework" }}`.
+// Other fields are passed on as they are.
+func evaluateTemplates(cfg *VercelConfig, site string, component string, environment string) error {
+	e := newTemplateEvaluator(cfg, site, component, environment)
+	return e.walk(reflect.ValueOf(cfg).Elem(), "", false)
+}
+
+// Evaluates the template expressions of the config of a single level, where
+// `{{ config "..." }}` returns the value of the effective config instead.
+func evaluateLevelTemplates(level *VercelConfig, effective *VercelConfig, site string, component string, environment string) error {
+	e := newTemplateEvaluator(level, site, component, environment)
+	e.funcs["config"] = newTemplateEvaluator(effective, site, component, environment).config
+	return e.walk(reflect.ValueOf(level).Elem(), "", false)
+}
+
+func newTemplateEvaluator(cfg *VercelConfig, site string, component string, environment string) *templateEvaluator {
+	e := &templateEvaluator{
+		cfg:        cfg,
+		values:     map[string]string{},
+		evaluating: map[string]bool{},
+	}
+	e.funcs = template.FuncMap{
+		"site":        func() string { return site },
+		"component":   func() string { return component },
+		"environment": func() string { return environment },
+		"config":      e.config,
+	}
+	return e
+}
+
+// Evaluates the strings of the template fields in a value in place
+func (e *templateEvaluator) walk(v reflect.Value, path string, evaluate bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		if !evaluate {
+			return nil
+		}
+		result, err := e.evaluate(path, v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(result)
+	case reflect.Struct:
+		// The values of dotenv files are taken literally
+		if env, ok := v.Interface().(ProjectEnvironmentVariable); ok && env.Source != "" {
+			return nil
+		}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.Type == reflect.TypeOf(unsetFields{}) || field.Tag.Get("merge") == mergeRuleIgnore {
+				continue
+			}
+			if err := e.walk(v.Field(i), joinPath(path, field.Tag.Get("mapstructure")), isTemplateField(field)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		// Lists and maps are shared with the configs of the levels, so they
+		// are copied before their items are evaluated
+		if v.IsNil() {
+			return nil
+		}
+		items := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(items, v)
+		v.Set(items)
+		for i := 0; i < v.Len(); i++ {
+			if err := e.walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i), evaluate); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := reflect.MakeMapWithSize(v.Type(), v.Len())
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			// Map entries can not be modified in place
+			entry := reflect.New(v.Type().Elem()).Elem()
+			entry.Set(v.MapIndex(key))
+			if err := e.walk(entry, joinPath(path, key.String()), evaluate); err != nil {
+				return err
+			}
+			entries.SetMapIndex(key, entry)
+		}
+		v.Set(entries)
+	}
+	return nil
+}
+
+func isTemplateField(field reflect.StructField) bool {
+	return field.Tag.Get("template") == "true"
+}
+
+// Returns the evaluated value of the string at a path
+func (e *templateEvaluator) evaluate(path string, value string) (string, error) {
+	if result, ok := e.values[path]; ok {
+		return result, nil
+	}
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	if e.evaluating[path] {
+		return "", fmt.Errorf("%s refers to itself", path)
+	}
+	e.evaluating[path] = true
+	defer delete(e.evaluating, path)
+
+	tmpl, err := template.New(path).Funcs(e.funcs).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	if err := tmpl.Execute(&result, nil); err != nil {
+		return "", err
+	}
+
+	e.values[path] = result.String()
+	return result.String(), nil
+}
+
+// Returns the effective value of the field at a path of mapstructure names,
+// such as project_config.name or projects.storybook.name
+func (e *templateEvaluator) config(path string) (any, error) {
+	v := reflect.ValueOf(e.cfg).Elem()
+	evaluate := false
+	for _, name := range strings.Split(path, ".") {
+		switch v.Kind() {
+		case reflect.Struct:
+			evaluate = false
+			for i := 0; i < v.NumField(); i++ {
+				if field := v.Type().Field(i); field.Tag.Get("mapstructure") == name {
+					evaluate = isTemplateField(field)
+				}
+			}
+			v = fieldByTag(v, name)
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(name))
+		default:
+			v = reflect.Value{}
+		}
+		if !v.IsValid() {
+			return nil, fmt.Errorf("unknown field %s", path)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if !evaluate {
+			return v.String(), nil
+		}
+		return e.evaluate(path, v.String())
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return v.Elem().Interface(), nil
+	}
+	return v.Interface(), nil
+}
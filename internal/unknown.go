@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Describes the configuration level of a site and component in errors
+func describeLevel(site string, component string) string {
+	switch {
+	case site == "":
+		return "global config"
+	case component == "":
+		return fmt.Sprintf("site config of %s", site)
+	}
+	return fmt.Sprintf("component config of %s in site %s", component, site)
+}
+
+// Returns an error listing the unknown fields found while decoding, each with
+// the closest known field of the same block as a suggestion.
+func unknownFieldsError(level string, unused []string) error {
+	if len(unused) == 0 {
+		return nil
+	}
+
+	paths := append([]string{}, unused...)
+	sort.Strings(paths)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "unknown fields in %s:", level)
+	for _, path := range paths {
+		fmt.Fprintf(&b, "\n  - %s: unknown field", path)
+		if suggestion := suggestField(reflect.TypeOf(VercelConfig{}), path); suggestion != "" {
+			fmt.Fprintf(&b, ", did you mean %s?", suggestion)
+		}
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// Returns an error listing the fields of a level, including its environment
+// overrides, which only apply to other levels, such as presets outside of the
+// global config.
+func misplacedFieldsError(level string, description string, data map[string]any) error {
+	paths := misplacedFields(level, data, "")
+	if len(paths) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "fields of other levels in %s:", description)
+	for _, path := range paths {
+		fmt.Fprintf(&b, "\n  - %s", path)
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+func misplacedFields(level string, data map[string]any, prefix string) []string {
+	var result []string
+	typ := reflect.TypeOf(VercelConfig{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		levels, ok := schemaOptions(field)["levels"]
+		if !ok || slices.Contains(strings.Split(levels, "|"), level) {
+			continue
+		}
+		name := field.Tag.Get("mapstructure")
+		if _, set := data[name]; !set {
+			continue
+		}
+		allowed := strings.ReplaceAll(levels, "|", " and ") + " level"
+		if strings.Contains(levels, "|") {
+			allowed += "s"
+		}
+		result = append(result, fmt.Sprintf("%s%s: only allowed on the %s", prefix, name, allowed))
+	}
+
+	environments, _ := data["environments"].(map[string]any)
+	for _, name := range sortedKeys(environments) {
+		if override, ok := environments[name].(map[string]any); ok {
+			result = append(result, misplacedFields(level, override, fmt.Sprintf("%senvironments[%s].", prefix, name))...)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Returns the known field closest to the last key of a path as reported by
+// mapstructure, such as project_config.domains[0].domian, or an empty string
+// when no field is close enough.
+func suggestField(typ reflect.Type, path string) string {
+	segments := splitPath(path)
+	for _, segment := range segments[:len(segments)-1] {
+		name, _, _ := strings.Cut(segment, "[")
+		field, ok := fieldByMapstructureTag(typ, name)
+		if !ok {
+			return ""
+		}
+
+		// Each index of a list or key of a map selects an element
+		typ = field.Type
+		for i := 0; i < strings.Count(segment, "["); i++ {
+			typ = typ.Elem()
+		}
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+	}
+	if typ.Kind() != reflect.Struct {
+		return ""
+	}
+
+	key := segments[len(segments)-1]
+	best, bestDistance := "", len(key)/3+2
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Type == reflect.TypeOf(unsetFields{}) || field.Tag.Get("mapstructure") == "-" {
+			continue
+		}
+		name := field.Tag.Get("mapstructure")
+		if distance := editDistance(key, name); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// Splits a path on the dots outside of map keys and list indexes
+func splitPath(path string) []string {
+	var result []string
+	depth, start := 0, 0
+	for i, r := range path {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				result = append(result, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(result, path[start:])
+}
+
+func fieldByMapstructureTag(typ reflect.Type, tag string) (reflect.StructField, bool) {
+	if typ.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Tag.Get("mapstructure") == tag {
+			return typ.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Returns the Levenshtein distance between two strings
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
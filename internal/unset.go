@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"reflect"
+	"sort"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// The value which clears a field inherited from the parent level, next to null
+const unsetValue = "!unset"
+
+// Names of the fields which are explicitly cleared on a level, as opposed to
+// being absent and inherited from the parent level
+type unsetFields []string
+
+// Moves the fields which are null or !unset to the `unset` field of the
+// structs which support clearing inherited values
+func unsetHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.Map || to.Kind() != reflect.Struct {
+		return data, nil
+	}
+	if field, ok := to.FieldByName("Unset"); !ok || field.Type != reflect.TypeOf(unsetFields{}) {
+		return data, nil
+	}
+	values, ok := data.(map[string]any)
+	if !ok {
+		return data, nil
+	}
+
+	result := make(map[string]any, len(values))
+	var unset []string
+	for key, value := range values {
+		if value == nil || value == unsetValue {
+			unset = append(unset, key)
+			continue
+		}
+		result[key] = value
+	}
+	if len(unset) > 0 {
+		sort.Strings(unset)
+		result["unset"] = unset
+	}
+	return result, nil
+}
+
+// Replaces the values tagged with the YAML tag !unset, which decode to an empty
+// string when the tag is not quoted, by the !unset string
+func resolveUnsetTags(node *yaml.Node) {
+	if node.Tag == unsetValue {
+		*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: unsetValue, Line: node.Line, Column: node.Column}
+		return
+	}
+	for _, child := range node.Content {
+		resolveUnsetTags(child)
+	}
+}
+
+// Reports whether the field is cleared
+func (u unsetFields) has(field string) bool {
+	return slices.Contains(u, field)
+}
+
+// Sets the cleared fields of the target struct to their zero value
+func (u unsetFields) clear(target any) {
+	if len(u) == 0 {
+		return
+	}
+	v := reflect.ValueOf(target).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if u.has(v.Type().Field(i).Tag.Get("mapstructure")) {
+			v.Field(i).SetZero()
+		}
+	}
+}
+
+// Returns the fields cleared by the result of merging the child into the
+// parent: the fields cleared by the child and the fields cleared by the parent
+// which the child does not set again. This keeps a cleared field cleared when
+// the merged result is merged into the next level.
+func (u unsetFields) inherit(parent unsetFields, child any) unsetFields {
+	result := slices.Clone(u)
+	for _, field := range parent {
+		if !result.has(field) && !fieldIsSet(reflect.ValueOf(child), []string{field}, "") {
+			result = append(result, field)
+		}
+	}
+	return result
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// The environments every Vercel project has
+var knownEnvironments = []string{"development", "preview", "production"}
+
+// System environment variables which Vercel sets on every deployment. Only
+// these exact keys are rejected, other keys with a VERCEL_ prefix are allowed
+// since some of them configure the build, e.g. VERCEL_FORCE_NO_BUILD_CACHE.
+// Keep the list in sync with the README.
+var reservedEnvironmentVariables = []string{
+	"VERCEL",
+	"VERCEL_ENV",
+	"VERCEL_URL",
+	"VERCEL_BRANCH_URL",
+	"VERCEL_PROJECT_PRODUCTION_URL",
+	"VERCEL_REGION",
+	"VERCEL_DEPLOYMENT_ID",
+	"VERCEL_SKEW_PROTECTION_ENABLED",
+	"VERCEL_AUTOMATION_BYPASS_SECRET",
+	"VERCEL_OIDC_TOKEN",
+	"VERCEL_GIT_PROVIDER",
+	"VERCEL_GIT_REPO_SLUG",
+	"VERCEL_GIT_REPO_OWNER",
+	"VERCEL_GIT_REPO_ID",
+	"VERCEL_GIT_COMMIT_REF",
+	"VERCEL_GIT_COMMIT_SHA",
+	"VERCEL_GIT_COMMIT_MESSAGE",
+	"VERCEL_GIT_COMMIT_AUTHOR_LOGIN",
+	"VERCEL_GIT_COMMIT_AUTHOR_NAME",
+	"VERCEL_GIT_PREVIOUS_SHA",
+	"VERCEL_GIT_PULL_REQUEST_ID",
+}
+
+// A violation found in the merged configuration of a project
+type violation struct {
+	path    string
+	message string
+	// Reports whether a project config of a level contains the bad value
+	origin func(project *ProjectConfig) bool
+}
+
+// Validates the merged configuration of a component and returns a single
+// error listing every violation with the levels the bad values came from.
+// The validation runs before the defaults are applied, so only configured
+// values are reported, and after the templates are evaluated, so the values
+// are checked as they are rendered.
+func (p *VercelPlugin) validateConfig(site string, component string) error {
+	cfg := p.mergeConfig(site, component)
+	if cfg == nil {
+		return nil
+	}
+	if err := evaluateTemplates(cfg, site, component, p.environment); err != nil {
+		return err
+	}
+
+	// The levels are evaluated as well to find the origins of the values. A
+	// level which can not be evaluated on its own is compared as configured.
+	levels := p.getLevels(site, component)
+	for i, level := range levels {
+		evaluated := level.config.clone()
+		if err := evaluateLevelTemplates(evaluated, cfg, site, component, p.environment); err == nil {
+			levels[i].config = evaluated
+		}
+	}
+
+	var lines []string
+	for _, project := range cfg.mergedProjects() {
+		prefix := "project_config"
+		if project.Name != "" {
+			prefix = "projects." + project.Name
+		}
+
+		for _, v := range project.Config.ProjectConfig.violations() {
+			var origins []string
+			for _, level := range levels {
+				for _, config := range level.config.projectConfigs(project.Name) {
+					if v.origin(config) {
+						origins = append(origins, level.name)
+						break
+					}
+				}
+			}
+			lines = append(lines, fmt.Sprintf("  - %s.%s: %s (from %s)", prefix, v.path, v.message, strings.Join(origins, ", ")))
+		}
+	}
+
+	lines = append(lines, p.unknownGroups(site, component)...)
+	lines = append(lines, p.presetErrors(site, component)...)
+
+	if len(lines) > 0 {
+		return fmt.Errorf("invalid configuration for component %s:\n%s", component, strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// Returns the project configs of a level which apply to the named project
+func (c *VercelConfig) projectConfigs(name string) []*ProjectConfig {
+	result := []*ProjectConfig{&c.ProjectConfig}
+	if project, ok := c.Projects[name]; ok && name != "" {
+		result = append(result, &project)
+	}
+	return result
+}
+
+func (c *ProjectConfig) violations() []violation {
+	var result []violation
+
+	domains := map[string]int{}
+	for _, d := range c.ProjectDomains {
+		domains[d.Domain]++
+	}
+	for i, d := range c.ProjectDomains {
+		domain := d.Domain
+		if domains[domain] > 1 && slices.IndexFunc(c.ProjectDomains, func(o ProjectDomain) bool { return o.Domain == domain }) == i {
+			result = append(result, violation{
+				path:    "domains",
+				message: fmt.Sprintf("duplicate domain %s", domain),
+				origin: func(project *ProjectConfig) bool {
+					return slices.ContainsFunc(project.ProjectDomains, func(o ProjectDomain) bool { return o.Domain == domain })
+				},
+			})
+		}
+
+		redirect := d.Redirect
+		if redirect != "" && domains[redirect] == 0 {
+			result = append(result, violation{
+				path:    fmt.Sprintf("domains[%s].redirect", domain),
+				message: fmt.Sprintf("redirect target %s is not a domain of the project", redirect),
+				origin: func(project *ProjectConfig) bool {
+					return slices.ContainsFunc(project.ProjectDomains, func(o ProjectDomain) bool {
+						return o.Domain == domain && o.Redirect == redirect
+					})
+				},
+			})
+		}
+	}
+
+	environments := append(slices.Clone(knownEnvironments), c.CustomEnvironments...)
+	for _, env := range c.EnvironmentVariables {
+		key := env.Key
+		if slices.Contains(reservedEnvironmentVariables, key) {
+			result = append(result, violation{
+				path:    fmt.Sprintf("environment_variables[%s]", key),
+				message: "key is reserved by Vercel",
+				origin:  func(project *ProjectConfig) bool { return project.hasEnvironmentVariable(key, "") },
+			})
+		}
+
+		for _, environment := range env.Environment {
+			if slices.Contains(environments, environment) {
+				continue
+			}
+			environment := environment
+			result = append(result, violation{
+				path:    fmt.Sprintf("environment_variables[%s].environment", key),
+				message: fmt.Sprintf("unknown environment %s, expected one of %s", environment, strings.Join(environments, ", ")),
+				origin:  func(project *ProjectConfig) bool { return project.hasEnvironmentVariable(key, environment) },
+			})
+		}
+	}
+
+	if c.PasswordProtection.DeploymentType != "" && c.PasswordProtection.Password == "" {
+		result = append(result, violation{
+			path:    "password_protection.deployment_type",
+			message: "deployment_type is set without a password",
+			origin: func(project *ProjectConfig) bool {
+				return project.PasswordProtection.DeploymentType != ""
+			},
+		})
+	}
+
+	if c.IgnoreCommand != "" && c.Ignore.command() != "" {
+		result = append(result, violation{
+			path:    "ignore",
+			message: "ignore and ignore_command cannot both be set",
+			origin: func(project *ProjectConfig) bool {
+				return project.IgnoreCommand != "" || project.Ignore.command() != ""
+			},
+		})
+	}
+
+	result = append(result, c.vercelJSONViolations()...)
+
+	return result
+}
+
+// Reports whether the project has an environment variable with the key, which
+// also targets the environment when it is given
+func (c *ProjectConfig) hasEnvironmentVariable(key string, environment string) bool {
+	return slices.ContainsFunc(c.EnvironmentVariables, func(env ProjectEnvironmentVariable) bool {
+		return env.Key == key && (environment == "" || slices.Contains(env.Environment, environment))
+	})
+}
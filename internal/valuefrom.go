@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/mach-composer/mach-composer-plugin-helpers/helpers"
+	"github.com/mitchellh/mapstructure"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/exp/slices"
+)
+
+// Matches the references to the outputs of other component modules
+var moduleReference = regexp.MustCompile(`\bmodule\.([A-Za-z0-9_-]+)\.`)
+
+// Returns the value of value_from as a single `${...}` interpolation, which is
+// rendered as the raw terraform expression, see terraformExpression
+func (v ValueFrom) expression() (string, error) {
+	switch {
+	case v.Expression != "" && v.Component == "" && v.Output == "":
+		if _, ok := terraformExpression(v.Expression); ok {
+			return v.Expression, nil
+		}
+		return fmt.Sprintf("${%s}", v.Expression), nil
+	case v.Expression == "" && v.Component != "" && v.Output != "":
+		return fmt.Sprintf("${module.%s.%s}", v.Component, v.Output), nil
+	}
+	return "", fmt.Errorf("value_from needs either an expression or a component and output")
+}
+
+// Sets the value of an environment variable given with value_from to its
+// expression, so it is merged like any other value
+func valueFromHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if to != reflect.TypeOf(ProjectEnvironmentVariable{}) {
+		return data, nil
+	}
+	m, ok := data.(map[string]any)
+	if !ok || m["value_from"] == nil {
+		return data, nil
+	}
+	if _, ok := m["value"]; ok {
+		return nil, fmt.Errorf("environment variable %v has both a value and value_from", m["key"])
+	}
+
+	var source ValueFrom
+	if err := mapstructure.Decode(m["value_from"], &source); err != nil {
+		return nil, err
+	}
+	expression, err := source.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any, len(m)+1)
+	for key, value := range m {
+		result[key] = value
+	}
+	result["value"] = expression
+	return result, nil
+}
+
+// Returns the modules of the other components whose outputs are used in the
+// environment variables of the projects, sorted by name.
+func componentDependencies(component string, projects []namedProject) []string {
+	var result []string
+	for _, project := range projects {
+		for _, env := range project.Config.ProjectConfig.EnvironmentVariables {
+			expression, ok := terraformExpression(env.Value)
+			if !ok {
+				continue
+			}
+			for _, match := range moduleReference.FindAllStringSubmatch(expression, -1) {
+				module := "module." + match[1]
+				if match[1] != component && !slices.Contains(result, module) {
+					result = append(result, module)
+				}
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Returns the terraform expression of a value which consists of a single
+// `${...}` interpolation. Braces and quoted strings within the expression are
+// skipped, so `${jsonencode({a = "}"})}` is a single interpolation while
+// `${a}-${b}` is not.
+func terraformExpression(value string) (string, bool) {
+	if !strings.HasPrefix(value, "${") {
+		return "", false
+	}
+
+	depth := 0
+	quoted := false
+	for i := 1; i < len(value); i++ {
+		switch c := value[i]; {
+		case quoted && c == '\\':
+			i++
+		case quoted:
+			quoted = c != '"'
+		case c == '"':
+			quoted = true
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			expression := value[2:i]
+			if i != len(value)-1 || strings.TrimSpace(expression) == "" {
+				return "", false
+			}
+			return expression, true
+		}
+	}
+	return "", false
+}
+
+// Renders an attribute with the HCL of a value. Strings in the value which are
+// a terraform expression are rendered as the raw expression, other strings are
+// quoted. In quoted strings `$${` stands for a literal `${`, as in terraform.
+func renderHCL(attribute string, value any) string {
+	var data any
+	body, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		panic(err)
+	}
+
+	// The expressions are replaced by placeholders, which are replaced by the
+	// raw expressions once the value is serialized. Strings containing `${`
+	// are quoted here, since helpers renders some of them unquoted.
+	var expressions []string
+	var replace func(v any) any
+	replace = func(v any) any {
+		switch v := v.(type) {
+		case string:
+			if expression, ok := terraformExpression(v); ok {
+				expressions = append(expressions, expression)
+				return fmt.Sprintf("__terraform_expression_%d__", len(expressions)-1)
+			}
+			if strings.Contains(v, "${") {
+				literal := strings.ReplaceAll(v, "$${", "${")
+				expressions = append(expressions, string(hclwrite.TokensForValue(cty.StringVal(literal)).Bytes()))
+				return fmt.Sprintf("__terraform_expression_%d__", len(expressions)-1)
+			}
+		case map[string]any:
+			for key, item := range v {
+				v[key] = replace(item)
+			}
+		case []any:
+			for i, item := range v {
+				v[i] = replace(item)
+			}
+		}
+		return v
+	}
+	data = replace(data)
+
+	result := strings.TrimSuffix(helpers.SerializeToHCL(attribute, data), "\n")
+	for i, expression := range expressions {
+		result = strings.Replace(result, fmt.Sprintf(`"__terraform_expression_%d__"`, i), expression, 1)
+	}
+	return result
+}
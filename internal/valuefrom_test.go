@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerraformExpression(t *testing.T) {
+	tests := []struct {
+		value      string
+		expression string
+		ok         bool
+	}{
+		{value: "${module.api.url}", expression: "module.api.url", ok: true},
+		{value: "${jsonencode({a = 1})}", expression: "jsonencode({a = 1})", ok: true},
+		{value: "${a ? b : c}", expression: "a ? b : c", ok: true},
+		{value: `${lookup(var.urls, "}")}`, expression: `lookup(var.urls, "}")`, ok: true},
+		{value: `${replace(var.name, "\"", "{")}`, expression: `replace(var.name, "\"", "{")`, ok: true},
+		{value: "${a}-${b}", ok: false},
+		{value: "https://${module.api.host}", ok: false},
+		{value: "${module.api.url", ok: false},
+		{value: "${}", ok: false},
+		{value: "plain", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			expression, ok := terraformExpression(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expression, expression)
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A terraform variable which is passed to the component module. The template
+// renders the assignment of the variable and is also the source for the
+// variable declaration a module needs.
+type componentVariable struct {
+	Name        string
+	Type        string
+	Description string
+	// HCL expression, only used for the declaration
+	Default   string
+	Sensitive bool
+	Template  string
+}
+
+var componentVariables = []componentVariable{
+	{
+		Name:        "vercel_team_id",
+		Type:        "string",
+		Description: "ID of the Vercel team the project belongs to",
+		Default:     "null",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_team_id") .TeamID }}`,
+	},
+	{
+		Name:        "vercel_project_name",
+		Type:        "string",
+		Description: "Name of the Vercel project",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_project_name") .ProjectConfig.Name }}`,
+	},
+	{
+		Name:        "vercel_project_framework",
+		Type:        "string",
+		Description: "Framework preset of the project",
+		Default:     "null",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_project_framework") .ProjectConfig.Framework }}`,
+	},
+	{
+		Name:        "vercel_project_build_command",
+		Type:        "string",
+		Description: "Command used to build the project",
+		Default:     "null",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_project_build_command") .ProjectConfig.BuildCommand }}`,
+	},
+	{
+		Name:        "vercel_project_ignore_command",
+		Type:        "string",
+		Description: "Command which determines whether a build should be skipped",
+		Default:     "null",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_project_ignore_command") .ProjectConfig.EffectiveIgnoreCommand }}`,
+	},
+	{
+		Name:        "vercel_project_root_directory",
+		Type:        "string",
+		Description: "Directory within the repository which contains the project",
+		Default:     "null",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_project_root_directory") .ProjectConfig.RootDirectory }}`,
+	},
+	{
+		Name:        "vercel_project_node_version",
+		Type:        "string",
+		Description: "Node.js version used for builds",
+		Default:     "null",
+		Template:    `{{ if .ProjectConfig.NodeVersion }}{{ renderProperty (print $.Prefix "vercel_project_node_version") .ProjectConfig.NodeVersion }}{{ end }}`,
+	},
+	{
+		Name:        "vercel_project_serverless_function_region",
+		Type:        "string",
+		Description: "Region in which serverless functions are deployed",
+		Default:     "null",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_project_serverless_function_region") .ProjectConfig.ServerlessFunctionRegion }}`,
+	},
+	{
+		Name:        "vercel_project_serverless_function_regions",
+		Type:        "list(string)",
+		Description: "Regions in which serverless functions are deployed",
+		Default:     "null",
+		Template:    `{{ if .ProjectConfig.ServerlessFunctionRegions }}{{ renderProperty (print $.Prefix "vercel_project_serverless_function_regions") .ProjectConfig.ServerlessFunctionRegions }}{{ end }}`,
+	},
+	{
+		Name:        "vercel_project_manual_production_deployment",
+		Type:        "bool",
+		Description: "Whether production deployments are created manually",
+		Default:     "false",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_project_manual_production_deployment") .ProjectConfig.ManualProductionDeployment }}`,
+	},
+	{
+		Name:        "vercel_project_protection_bypass_for_automation",
+		Type:        "bool",
+		Description: "Whether automation may bypass deployment protection",
+		Default:     "false",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_project_protection_bypass_for_automation") .ProjectConfig.ProtectionBypassForAutomation }}`,
+	},
+	{
+		Name:        "vercel_project_vercel_authentication",
+		Type:        "object({ deployment_type = string })",
+		Description: "Vercel authentication settings of the project",
+		Default:     "null",
+		Template: `{{ $.Prefix }}vercel_project_vercel_authentication = {
+			{{ renderProperty "deployment_type" .ProjectConfig.VercelAuthentication.DeploymentType }}
+		}`,
+	},
+	{
+		Name:        "vercel_project_password_protection",
+		Type:        "object({ password = string, deployment_type = string })",
+		Description: "Password protection settings of the project",
+		Default:     "null",
+		Sensitive:   true,
+		Template: `{{ $.Prefix }}vercel_project_password_protection = {
+			{{ renderProperty "password" .ProjectConfig.PasswordProtection.Password }}
+			{{ renderProperty "deployment_type" .ProjectConfig.PasswordProtection.DeploymentType }}
+		}`,
+	},
+	{
+		Name:        "vercel_project_git_repository",
+		Type:        "object({ production_branch = string, type = string, repo = string })",
+		Description: "Git repository connected to the project",
+		Default:     "null",
+		Template: `{{ $.Prefix }}vercel_project_git_repository = {
+			{{ renderProperty "production_branch" .ProjectConfig.GitRepository.ProductionBranch }}
+			{{ renderProperty "type" .ProjectConfig.GitRepository.Type }}
+			{{ renderProperty "repo" .ProjectConfig.GitRepository.Repo }}
+		}`,
+	},
+	{
+		Name:        "vercel_project_environment_variables",
+		Type:        "list(object({ key = string, value = string, environment = list(string) }))",
+		Description: "Environment variables of the project when rendered inline",
+		Default:     "[]",
+		Template: `{{ $.Prefix }}vercel_project_environment_variables = [{{ if eq .ProjectConfig.EnvironmentVariablesMode "inline" }}{{range .ProjectConfig.EnvironmentVariables }}
+			{
+				{{ renderProperty "key" .Key }}
+				{{ .DisplayValue }}
+				{{ .DisplayEnvironments }}
+			},{{end}}{{end}}
+		]`,
+	},
+	{
+		Name:        "vercel_project_bulk_environment_variables",
+		Type:        "list(object({ key = string, value = string, target = list(string) }))",
+		Description: "Environment variables of the project when environment_variables_mode is bulk",
+		Default:     "null",
+		Template: `{{ if eq .ProjectConfig.EnvironmentVariablesMode "bulk" }}{{ $.Prefix }}vercel_project_bulk_environment_variables = [{{range .ProjectConfig.EnvironmentVariables }}
+			{
+				{{ renderProperty "key" .Key }}
+				{{ .DisplayValue }}
+				{{ .DisplayTargets }}
+			},{{end}}
+		]{{ end }}`,
+	},
+	{
+		Name:        "vercel_project_domains",
+		Type:        "list(object({ domain = string, redirect_status_code = number }))",
+		Description: "Domains of the project",
+		Default:     "[]",
+		Template: `{{ $.Prefix }}vercel_project_domains = [{{range .ProjectConfig.ProjectDomains }}
+			{
+				{{ renderProperty "domain" .Domain }}
+				{{ renderProperty "redirect_status_code" .RedirectStatusCode }}
+			},{{end}}
+		]`,
+	},
+	{
+		Name:        "vercel_project_rolling_release",
+		Type:        "object({ advancement_type = string, stages = list(object({ target_percentage = number, duration = optional(number) })) })",
+		Description: "Rolling release settings of the project",
+		Default:     "null",
+		Template: `{{ with .ProjectConfig.RollingRelease }}{{ if .Stages }}{{ $.Prefix }}vercel_project_rolling_release = {
+			{{ renderProperty "advancement_type" .AdvancementType }}
+			stages = [{{ range .Stages }}
+				{
+					{{ renderProperty "target_percentage" .TargetPercentage }}
+					{{ if .Duration }}{{ renderProperty "duration" .Duration }}{{ end }}
+				},{{ end }}
+			]
+		}{{ end }}{{ end }}`,
+	},
+	{
+		Name:        "vercel_project_vercel_json",
+		Type:        "string",
+		Description: "Contents of the vercel.json with the routes, crons and functions of the project",
+		Default:     "null",
+		Template:    `{{ with .ProjectConfig.VercelJSON }}{{ renderProperty (print $.Prefix "vercel_project_vercel_json") . }}{{ end }}`,
+	},
+}
+
+// The variables passed to the component module in managed mode
+var managedVariables = []componentVariable{
+	{
+		Name:        "vercel_team_id",
+		Type:        "string",
+		Description: "ID of the Vercel team the project belongs to",
+		Default:     "null",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_team_id") .TeamID }}`,
+	},
+	{
+		Name:        "vercel_project_id",
+		Type:        "string",
+		Description: "ID of the Vercel project managed by the plugin",
+		Template:    `{{ $.Prefix }}vercel_project_id = vercel_project.{{ .ResourceName }}.id`,
+	},
+	{
+		Name:        "vercel_project_name",
+		Type:        "string",
+		Description: "Name of the Vercel project managed by the plugin",
+		Template:    `{{ $.Prefix }}vercel_project_name = vercel_project.{{ .ResourceName }}.name`,
+	},
+	{
+		Name:        "vercel_project_manual_production_deployment",
+		Type:        "bool",
+		Description: "Whether production deployments are created manually",
+		Default:     "false",
+		Template:    `{{ renderProperty (print $.Prefix "vercel_project_manual_production_deployment") .ProjectConfig.ManualProductionDeployment }}`,
+	},
+	{
+		Name:        "vercel_project_vercel_json",
+		Type:        "string",
+		Description: "Contents of the vercel.json with the routes, crons and functions of the project",
+		Default:     "null",
+		Template:    `{{ with .ProjectConfig.VercelJSON }}{{ renderProperty (print $.Prefix "vercel_project_vercel_json") . }}{{ end }}`,
+	},
+}
+
+// Returns the variables passed to the component module in the object output
+// format of the given version
+func objectVariables(version int) []componentVariable {
+	return []componentVariable{
+		{
+			Name:        "vercel_project_config",
+			Type:        projectObjectType(version),
+			Description: "Configuration of the Vercel project",
+			Sensitive:   true,
+			Template:    `{{ .RenderProjectObject }}`,
+		},
+	}
+}
+
+// Returns the variables passed to the component module in the object output
+// format of the given version when the component has multiple projects
+func objectProjectsVariables(version int) []componentVariable {
+	return []componentVariable{
+		{
+			Name:        "vercel_projects",
+			Type:        "map(" + projectObjectType(version) + ")",
+			Description: "Configuration of the Vercel projects by logical name",
+			Sensitive:   true,
+			Template:    `{{ .RenderProjectObjects }}`,
+		},
+	}
+}
+
+// Combines the templates of the variables into a single template
+func variablesTemplate(variables []componentVariable) string {
+	var sb strings.Builder
+	sb.WriteString("\n")
+	for _, v := range variables {
+		sb.WriteString("\t\t")
+		sb.WriteString(v.Template)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\t")
+	return sb.String()
+}
+
+// VariablesFileOptions describes the component for which RenderVariablesFile
+// renders the declarations.
+type VariablesFileOptions struct {
+	Mode   string
+	Format string
+	// Version of the object output format, defaults to 1
+	FormatVersion int
+	// Logical names of the projects when the component has multiple projects
+	Projects []string
+}
+
+// RenderVariablesFile returns the terraform variable declarations a component
+// module needs to accept the variables rendered for the given options.
+func RenderVariablesFile(opts VariablesFileOptions) (string, error) {
+	var variables []componentVariable
+	switch opts.Mode {
+	case "", modeVariables:
+		switch opts.Format {
+		case "", outputFormatFlat:
+			variables = componentVariables
+		case outputFormatObject:
+			version := opts.FormatVersion
+			if version == 0 {
+				version = 1
+			}
+			if err := checkFormatVersion(version); err != nil {
+				return "", err
+			}
+			variables = objectVariables(version)
+			if len(opts.Projects) > 0 {
+				variables = objectProjectsVariables(version)
+				opts.Projects = nil
+			}
+		default:
+			return "", fmt.Errorf("unknown output format %q, expected %s or %s", opts.Format, outputFormatFlat, outputFormatObject)
+		}
+	case modeManaged:
+		variables = managedVariables
+	default:
+		return "", fmt.Errorf("unknown mode %q, expected %s or %s", opts.Mode, modeVariables, modeManaged)
+	}
+
+	prefixes := []string{""}
+	if len(opts.Projects) > 0 {
+		prefixes = prefixes[:0]
+		for _, name := range opts.Projects {
+			prefixes = append(prefixes, projectPrefix(name))
+		}
+	}
+
+	blocks := make([]string, 0, len(variables)*len(prefixes))
+	for _, prefix := range prefixes {
+		for _, v := range variables {
+			blocks = append(blocks, v.declaration(prefix))
+		}
+	}
+	return strings.Join(blocks, "\n"), nil
+}
+
+func (v componentVariable) declaration(prefix string) string {
+	attributes := [][2]string{
+		{"type", v.Type},
+		{"description", fmt.Sprintf("%q", v.Description)},
+	}
+	if v.Default != "" {
+		attributes = append(attributes, [2]string{"default", v.Default})
+	}
+	if v.Sensitive {
+		attributes = append(attributes, [2]string{"sensitive", "true"})
+	}
+
+	// Align the equal signs the same way terraform fmt does
+	width := 0
+	for _, a := range attributes {
+		width = max(width, len(a[0]))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "variable %q {\n", prefix+v.Name)
+	for _, a := range attributes {
+		fmt.Fprintf(&sb, "  %-*s = %s\n", width, a[0], a[1])
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
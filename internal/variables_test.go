@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderVariablesFile(t *testing.T) {
+	t.Run("declares every rendered variable", func(t *testing.T) {
+		plugin := NewVercelPlugin()
+		require.NoError(t, plugin.Configure("test", "3.3.0"))
+
+		err := plugin.SetSiteConfig("my-site", map[string]any{
+			"team_id": "test-team",
+			"project_config": map[string]any{
+				"name":                        "my-project",
+				"node_version":                "20.x",
+				"serverless_function_regions": []any{"fra1"},
+				"environment_variables_mode":  "bulk",
+				"rolling_release": map[string]any{
+					"stages": []any{map[string]any{"target_percentage": 100}},
+				},
+				"crons": []any{map[string]any{"path": "/api/cron", "schedule": "0 5 * * *"}},
+			},
+		})
+		require.NoError(t, err)
+
+		component, err := plugin.RenderTerraformComponent("my-site", "my-component")
+		require.NoError(t, err)
+
+		declarations, err := RenderVariablesFile(VariablesFileOptions{Mode: "variables", Format: "flat"})
+		require.NoError(t, err)
+
+		assignments := regexp.MustCompile(`(?m)^\t\t(\w+) = `).FindAllStringSubmatch(component.Variables, -1)
+		assert.Len(t, assignments, len(componentVariables))
+		for _, match := range assignments {
+			assert.Contains(t, declarations, "variable \""+match[1]+"\" {")
+		}
+	})
+
+	t.Run("renders declarations", func(t *testing.T) {
+		declarations, err := RenderVariablesFile(VariablesFileOptions{Mode: "managed"})
+		require.NoError(t, err)
+
+		assert.Contains(t, declarations, "variable \"vercel_project_id\" {\n  type        = string\n  description = \"ID of the Vercel project managed by the plugin\"\n}\n")
+		assert.Contains(t, declarations, "  default     = false\n")
+	})
+
+	t.Run("declares the object of the format version", func(t *testing.T) {
+		declarations, err := RenderVariablesFile(VariablesFileOptions{Format: "object"})
+		require.NoError(t, err)
+		assert.Contains(t, declarations, "format_version                   = number")
+		assert.NotContains(t, declarations, "vercel_json")
+
+		declarations, err = RenderVariablesFile(VariablesFileOptions{Format: "object", FormatVersion: 2})
+		require.NoError(t, err)
+		assert.Contains(t, declarations, "vercel_json                      = optional(string)")
+
+		_, err = RenderVariablesFile(VariablesFileOptions{Format: "object", FormatVersion: 3})
+		assert.EqualError(t, err, "unsupported format_version 3, expected 1 to 2")
+	})
+
+	t.Run("marks sensitive variables", func(t *testing.T) {
+		declarations, err := RenderVariablesFile(VariablesFileOptions{})
+		require.NoError(t, err)
+
+		assert.Contains(t, declarations, "variable \"vercel_project_password_protection\" {\n  type        = object({ password = string, deployment_type = string })\n  description = \"Password protection settings of the project\"\n  default     = null\n  sensitive   = true\n}\n")
+	})
+
+	t.Run("prefixes the variables of multiple projects", func(t *testing.T) {
+		declarations, err := RenderVariablesFile(VariablesFileOptions{Projects: []string{"storefront", "storybook"}})
+		require.NoError(t, err)
+
+		assert.Contains(t, declarations, "variable \"storefront_vercel_project_name\" {")
+		assert.Contains(t, declarations, "variable \"storybook_vercel_project_name\" {")
+		assert.NotContains(t, declarations, "variable \"vercel_project_name\" {")
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		_, err := RenderVariablesFile(VariablesFileOptions{Mode: "other"})
+		assert.ErrorContains(t, err, "unknown mode \"other\"")
+	})
+}
@@ -0,0 +1,387 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// The limits of Vercel on the routes, crons and functions of a project
+const (
+	maxRoutes            = 2048
+	maxRouteLength       = 4096
+	maxCrons             = 40
+	maxCronPathLength    = 512
+	minFunctionMemory    = 128
+	maxFunctionMemory    = 3009
+	maxFunctionDuration  = 900
+	vercelJSONSchemaLink = "https://openapi.vercel.sh/vercel.json"
+)
+
+// The ranges of the fields of a cron schedule: minute, hour, day of month,
+// month and day of week
+var cronFieldRanges = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// The vercel.json of a project, limited to the settings the plugin manages
+type vercelJSON struct {
+	Schema    string                        `json:"$schema"`
+	Headers   []vercelJSONHeaders           `json:"headers,omitempty"`
+	Redirects []vercelJSONRedirect          `json:"redirects,omitempty"`
+	Rewrites  []vercelJSONRewrite           `json:"rewrites,omitempty"`
+	Crons     []vercelJSONCron              `json:"crons,omitempty"`
+	Functions map[string]vercelJSONFunction `json:"functions,omitempty"`
+}
+
+type vercelJSONHeaders struct {
+	Source  string             `json:"source"`
+	Headers []vercelJSONHeader `json:"headers"`
+}
+
+type vercelJSONHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type vercelJSONRedirect struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Permanent   *bool  `json:"permanent,omitempty"`
+	StatusCode  int64  `json:"statusCode,omitempty"`
+}
+
+type vercelJSONRewrite struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+type vercelJSONCron struct {
+	Path     string `json:"path"`
+	Schedule string `json:"schedule"`
+}
+
+type vercelJSONFunction struct {
+	Memory      int64 `json:"memory,omitempty"`
+	MaxDuration int64 `json:"maxDuration,omitempty"`
+}
+
+// Reports whether the project configures any setting of the vercel.json
+func (c *ProjectConfig) hasVercelJSON() bool {
+	r := c.Routing
+	return len(r.Headers) > 0 || len(r.Redirects) > 0 || len(r.Rewrites) > 0 || len(c.Crons) > 0 || len(c.Functions) > 0
+}
+
+func (c *ProjectConfig) vercelJSON() vercelJSON {
+	result := vercelJSON{Schema: vercelJSONSchemaLink}
+
+	for _, route := range c.Routing.Headers {
+		headers := vercelJSONHeaders{Source: route.Source, Headers: []vercelJSONHeader{}}
+		for _, header := range route.Headers {
+			headers.Headers = append(headers.Headers, vercelJSONHeader{Key: header.Key, Value: header.Value})
+		}
+		result.Headers = append(result.Headers, headers)
+	}
+
+	for _, redirect := range c.Routing.Redirects {
+		result.Redirects = append(result.Redirects, vercelJSONRedirect{
+			Source:      redirect.Source,
+			Destination: redirect.Destination,
+			Permanent:   redirect.Permanent,
+			StatusCode:  redirect.StatusCode,
+		})
+	}
+
+	for _, rewrite := range c.Routing.Rewrites {
+		result.Rewrites = append(result.Rewrites, vercelJSONRewrite{Source: rewrite.Source, Destination: rewrite.Destination})
+	}
+
+	for _, cron := range c.Crons {
+		result.Crons = append(result.Crons, vercelJSONCron{Path: cron.Path, Schedule: cron.Schedule})
+	}
+
+	if len(c.Functions) > 0 {
+		result.Functions = make(map[string]vercelJSONFunction, len(c.Functions))
+		for source, function := range c.Functions {
+			result.Functions[source] = vercelJSONFunction{Memory: function.Memory, MaxDuration: function.MaxDuration}
+		}
+	}
+
+	return result
+}
+
+// Returns the vercel.json of the project, or an empty string when the project
+// configures none of its settings
+func (c *ProjectConfig) VercelJSON() string {
+	if !c.hasVercelJSON() {
+		return ""
+	}
+	body, err := json.MarshalIndent(c.vercelJSON(), "", "  ")
+	if err != nil {
+		// The vercel.json only consists of strings, numbers and booleans
+		panic(err)
+	}
+	return string(body) + "\n"
+}
+
+// Returns the violations of the limits of Vercel on the routes, crons and
+// functions of the project
+func (c *ProjectConfig) vercelJSONViolations() []violation {
+	var result []violation
+
+	routes := len(c.Routing.Headers) + len(c.Routing.Redirects) + len(c.Routing.Rewrites)
+	if routes > maxRoutes {
+		result = append(result, violation{
+			path:    "routing",
+			message: fmt.Sprintf("%d routes exceed the limit of %d", routes, maxRoutes),
+			origin: func(project *ProjectConfig) bool {
+				r := project.Routing
+				return len(r.Headers) > 0 || len(r.Redirects) > 0 || len(r.Rewrites) > 0
+			},
+		})
+	}
+
+	for _, route := range c.Routing.Headers {
+		source := route.Source
+		result = append(result, routeViolations("headers", source, "", func(project *ProjectConfig) bool {
+			return slices.ContainsFunc(project.Routing.Headers, func(o RouteHeaders) bool { return o.Source == source })
+		})...)
+	}
+
+	for _, redirect := range c.Routing.Redirects {
+		source := redirect.Source
+		origin := func(project *ProjectConfig) bool {
+			return slices.ContainsFunc(project.Routing.Redirects, func(o Redirect) bool { return o.Source == source })
+		}
+		result = append(result, routeViolations("redirects", source, redirect.Destination, origin)...)
+
+		if redirect.Permanent != nil && redirect.StatusCode != 0 {
+			result = append(result, violation{
+				path:    fmt.Sprintf("routing.redirects[%s]", source),
+				message: "permanent and status_code cannot both be set",
+				origin:  origin,
+			})
+		}
+	}
+
+	for _, rewrite := range c.Routing.Rewrites {
+		source := rewrite.Source
+		result = append(result, routeViolations("rewrites", source, rewrite.Destination, func(project *ProjectConfig) bool {
+			return slices.ContainsFunc(project.Routing.Rewrites, func(o Rewrite) bool { return o.Source == source })
+		})...)
+	}
+
+	if len(c.Crons) > maxCrons {
+		result = append(result, violation{
+			path:    "crons",
+			message: fmt.Sprintf("%d crons exceed the limit of %d", len(c.Crons), maxCrons),
+			origin:  func(project *ProjectConfig) bool { return len(project.Crons) > 0 },
+		})
+	}
+
+	for _, cron := range c.Crons {
+		path := cron.Path
+		origin := func(project *ProjectConfig) bool {
+			return slices.ContainsFunc(project.Crons, func(o Cron) bool { return o.Path == path })
+		}
+		if !strings.HasPrefix(path, "/") {
+			result = append(result, violation{
+				path:    fmt.Sprintf("crons[%s].path", path),
+				message: "path must start with /",
+				origin:  origin,
+			})
+		}
+		if len(path) > maxCronPathLength {
+			result = append(result, violation{
+				path:    fmt.Sprintf("crons[%s].path", path),
+				message: fmt.Sprintf("path is longer than %d characters", maxCronPathLength),
+				origin:  origin,
+			})
+		}
+		if err := validateCronSchedule(cron.Schedule); err != nil {
+			result = append(result, violation{
+				path:    fmt.Sprintf("crons[%s].schedule", path),
+				message: err.Error(),
+				origin:  origin,
+			})
+		}
+	}
+
+	sources := make([]string, 0, len(c.Functions))
+	for source := range c.Functions {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		source, function := source, c.Functions[source]
+		if function.Memory != 0 && (function.Memory < minFunctionMemory || function.Memory > maxFunctionMemory) {
+			result = append(result, violation{
+				path:    fmt.Sprintf("functions[%s].memory", source),
+				message: fmt.Sprintf("memory must be between %d and %d", minFunctionMemory, maxFunctionMemory),
+				origin:  func(project *ProjectConfig) bool { return project.Functions[source].Memory != 0 },
+			})
+		}
+		if function.MaxDuration != 0 && (function.MaxDuration < 1 || function.MaxDuration > maxFunctionDuration) {
+			result = append(result, violation{
+				path:    fmt.Sprintf("functions[%s].max_duration", source),
+				message: fmt.Sprintf("max_duration must be between 1 and %d", maxFunctionDuration),
+				origin:  func(project *ProjectConfig) bool { return project.Functions[source].MaxDuration != 0 },
+			})
+		}
+	}
+
+	return result
+}
+
+// Returns the violations of a route with the given source and destination
+func routeViolations(kind string, source string, destination string, origin func(project *ProjectConfig) bool) []violation {
+	var result []violation
+	if !strings.HasPrefix(source, "/") {
+		result = append(result, violation{
+			path:    fmt.Sprintf("routing.%s[%s].source", kind, source),
+			message: "source must start with /",
+			origin:  origin,
+		})
+	}
+	if len(source) > maxRouteLength {
+		result = append(result, violation{
+			path:    fmt.Sprintf("routing.%s[%s].source", kind, source),
+			message: fmt.Sprintf("source is longer than %d characters", maxRouteLength),
+			origin:  origin,
+		})
+	}
+	if len(destination) > maxRouteLength {
+		result = append(result, violation{
+			path:    fmt.Sprintf("routing.%s[%s].destination", kind, source),
+			message: fmt.Sprintf("destination is longer than %d characters", maxRouteLength),
+			origin:  origin,
+		})
+	}
+	return result
+}
+
+// Checks a cron schedule against the expressions Vercel supports: five fields
+// of numbers, ranges, lists and steps, without names for days and months,
+// and with either the day of month or the day of week set.
+func validateCronSchedule(schedule string) error {
+	fields := strings.Fields(schedule)
+	if len(fields) != len(cronFieldRanges) {
+		return fmt.Errorf("schedule %q must have %d fields", schedule, len(cronFieldRanges))
+	}
+
+	for i, field := range fields {
+		r := cronFieldRanges[i]
+		for _, part := range strings.Split(field, ",") {
+			if err := validateCronPart(part, r.min, r.max); err != nil {
+				return fmt.Errorf("schedule %q: %s %s", schedule, r.name, err)
+			}
+		}
+	}
+
+	if fields[2] != "*" && fields[4] != "*" {
+		return fmt.Errorf("schedule %q cannot set both the day of month and the day of week", schedule)
+	}
+	return nil
+}
+
+// Checks a single part of a cron field: *, a number or a range, optionally
+// with a step
+func validateCronPart(part string, min int, max int) error {
+	values, step, hasStep := strings.Cut(part, "/")
+	if hasStep {
+		n, err := strconv.Atoi(step)
+		if err != nil || n < 1 {
+			return fmt.Errorf("has an invalid step %q", step)
+		}
+	}
+	if values == "*" {
+		return nil
+	}
+
+	from, to, isRange := strings.Cut(values, "-")
+	bounds := []string{from}
+	if isRange {
+		bounds = append(bounds, to)
+	}
+	for _, bound := range bounds {
+		n, err := strconv.Atoi(bound)
+		if err != nil {
+			return fmt.Errorf("has an invalid value %q", bound)
+		}
+		if n < min || n > max {
+			return fmt.Errorf("value %d is not between %d and %d", n, min, max)
+		}
+	}
+	return nil
+}
+
+// VercelJSONOptions describes the configuration for which WriteVercelJSON
+// writes the vercel.json files.
+type VercelJSONOptions struct {
+	// Path of the mach-composer configuration file
+	File string
+	// Environment to apply the overrides of, defaults to the environment of
+	// the configuration file
+	Environment string
+	// Directory to write the files to
+	Output string
+}
+
+// WriteVercelJSON writes the effective vercel.json of every project to
+// <output>/<site>/<component>/vercel.json, with the logical name of the
+// project appended to the directory when a component has multiple projects.
+// Projects without any setting of the vercel.json are skipped. It returns
+// the paths of the written files.
+func WriteVercelJSON(opts VercelJSONOptions) ([]string, error) {
+	p, config, err := loadConfigFile(opts.File, opts.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, site := range config.Sites {
+		for _, component := range site.Components {
+			cfg, err := p.getConfig(site.Identifier, component.Name)
+			if err != nil {
+				return nil, fmt.Errorf("component %s: %w", component.Name, err)
+			}
+			if cfg == nil {
+				continue
+			}
+			if err := p.validateConfig(site.Identifier, component.Name); err != nil {
+				return nil, err
+			}
+
+			for _, project := range cfg.projects() {
+				body := project.Config.ProjectConfig.VercelJSON()
+				if body == "" {
+					continue
+				}
+
+				dir := filepath.Join(opts.Output, site.Identifier, component.Name, project.Name)
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					return nil, err
+				}
+				path := filepath.Join(dir, "vercel.json")
+				if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+					return nil, err
+				}
+				written = append(written, path)
+			}
+		}
+	}
+	return written, nil
+}
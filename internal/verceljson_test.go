@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCronSchedule(t *testing.T) {
+	for _, schedule := range []string{"* * * * *", "0 5 * * *", "*/15 8-18 * * 1-5", "0,30 0 1,15 * *", "0 0 1-7/2 1-12 *"} {
+		assert.NoError(t, validateCronSchedule(schedule), schedule)
+	}
+
+	for schedule, message := range map[string]string{
+		"0 5 * *":        `schedule "0 5 * *" must have 5 fields`,
+		"60 * * * *":     `schedule "60 * * * *": minute value 60 is not between 0 and 59`,
+		"0 0 0 * *":      `schedule "0 0 0 * *": day of month value 0 is not between 1 and 31`,
+		"0 0 * * SUN":    `schedule "0 0 * * SUN": day of week has an invalid value "SUN"`,
+		"*/0 * * * *":    `schedule "*/0 * * * *": minute has an invalid step "0"`,
+		"0 0 1 * 1":      `schedule "0 0 1 * 1" cannot set both the day of month and the day of week`,
+		"@daily":         `schedule "@daily" must have 5 fields`,
+		"0 0 * 1-13 * ":  `schedule "0 0 * 1-13 * ": month value 13 is not between 1 and 12`,
+		"0 0 * * 7":      `schedule "0 0 * * 7": day of week value 7 is not between 0 and 6`,
+		"0 0,24 * * *":   `schedule "0 0,24 * * *": hour value 24 is not between 0 and 23`,
+		"0 -1 * * *":     `schedule "0 -1 * * *": hour has an invalid value ""`,
+		"0 0 * * 1/":     `schedule "0 0 * * 1/": day of week has an invalid step ""`,
+		"0 0 ? * *":      `schedule "0 0 ? * *": day of month has an invalid value "?"`,
+		"0 0 * * 1-2-3":  `schedule "0 0 * * 1-2-3": day of week has an invalid value "2-3"`,
+		"0 0 L * *":      `schedule "0 0 L * *": day of month has an invalid value "L"`,
+		"0 0 * * * 2024": `schedule "0 0 * * * 2024" must have 5 fields`,
+	} {
+		err := validateCronSchedule(schedule)
+		if assert.Error(t, err, schedule) {
+			assert.Equal(t, message, err.Error())
+		}
+	}
+}
+
+func TestWriteVercelJSON(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.yml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+global:
+  environment: test
+  vercel:
+    project_config:
+      crons:
+        - path: /api/cleanup
+          schedule: "0 3 * * *"
+sites:
+  - identifier: my-site
+    components:
+      - name: my-component
+        vercel:
+          project_config:
+            name: my-project
+      - name: my-other-component
+        vercel:
+          project_config:
+            name: my-other-project
+          projects:
+            storefront:
+              name: storefront
+`), 0o644))
+
+	output := filepath.Join(dir, "out")
+	written, err := WriteVercelJSON(VercelJSONOptions{File: file, Output: output})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(output, "my-site", "my-component", "vercel.json"),
+		filepath.Join(output, "my-site", "my-other-component", "storefront", "vercel.json"),
+	}, written)
+
+	body, err := os.ReadFile(written[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"path": "/api/cleanup"`)
+}
@@ -1,11 +1,100 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/mach-composer/mach-composer-plugin-sdk/plugin"
 	"github.com/mach-composer/mach-composer-plugin-vercel/internal"
 )
 
 func main() {
+	// mach-composer starts the plugin without arguments, any argument is a
+	// command for use outside of mach-composer
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	p := internal.NewVercelPlugin()
 	plugin.ServePlugin(p)
 }
+
+func runCommand(name string, args []string) error {
+	switch name {
+	case "variables":
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		mode := fs.String("mode", "variables", "mode of the component: variables or managed")
+		format := fs.String("format", "flat", "output format of the variables: flat or object")
+		formatVersion := fs.Int("format-version", 1, "version of the object output format")
+		projects := fs.String("projects", "", "comma separated logical names when the component has multiple projects")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		opts := internal.VariablesFileOptions{Mode: *mode, Format: *format, FormatVersion: *formatVersion}
+		if *projects != "" {
+			opts.Projects = strings.Split(*projects, ",")
+		}
+
+		result, err := internal.RenderVariablesFile(opts)
+		if err != nil {
+			return err
+		}
+		fmt.Print(result)
+		return nil
+	case "explain":
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		file := fs.String("f", "main.yml", "mach-composer configuration file")
+		environment := fs.String("e", "", "environment to apply the overrides of, defaults to global.environment")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 2 {
+			return fmt.Errorf("usage: explain [-f file] [-e environment] <site> <component>")
+		}
+
+		result, err := internal.Explain(internal.ExplainOptions{
+			File:        *file,
+			Environment: *environment,
+			Site:        fs.Arg(0),
+			Component:   fs.Arg(1),
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Print(result)
+		return nil
+	case "vercel-json":
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		file := fs.String("f", "main.yml", "mach-composer configuration file")
+		environment := fs.String("e", "", "environment to apply the overrides of, defaults to global.environment")
+		output := fs.String("o", ".", "directory to write the vercel.json files to")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 0 {
+			return fmt.Errorf("usage: vercel-json [-f file] [-e environment] [-o directory]")
+		}
+
+		written, err := internal.WriteVercelJSON(internal.VercelJSONOptions{
+			File:        *file,
+			Environment: *environment,
+			Output:      *output,
+		})
+		if err != nil {
+			return err
+		}
+		for _, path := range written {
+			fmt.Println(path)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, available commands: variables, explain, vercel-json", name)
+	}
+}